@@ -9,12 +9,14 @@ import (
 
 // DefaultMemcachedFactory Creates Redis components by their descriptors.
 // See MemcachedCache
+// See NearMemcachedCache
 // See MemcachedLock
 type DefaultMemcachedFactory struct {
 	*cbuild.Factory
-	Descriptor               *cref.Descriptor
-	MemcachedCacheDescriptor *cref.Descriptor
-	MemcachedLockDescriptor  *cref.Descriptor
+	Descriptor                   *cref.Descriptor
+	MemcachedCacheDescriptor     *cref.Descriptor
+	NearMemcachedCacheDescriptor *cref.Descriptor
+	MemcachedLockDescriptor      *cref.Descriptor
 }
 
 // NewDefaultMemcachedFactory Create a new instance of the factory.
@@ -24,9 +26,11 @@ func NewDefaultMemcachedFactory() *DefaultMemcachedFactory {
 
 	c.Descriptor = cref.NewDescriptor("pip-services", "factory", "memcached", "default", "1.0")
 	c.MemcachedCacheDescriptor = cref.NewDescriptor("pip-services", "cache", "memcached", "*", "1.0")
+	c.NearMemcachedCacheDescriptor = cref.NewDescriptor("pip-services", "cache", "near-memcached", "*", "1.0")
 	c.MemcachedLockDescriptor = cref.NewDescriptor("pip-services", "lock", "memcached", "*", "1.0")
 
 	c.RegisterType(c.MemcachedCacheDescriptor, memcache.NewMemcachedCache[any])
+	c.RegisterType(c.NearMemcachedCacheDescriptor, memcache.NewNearMemcachedCache[any])
 	c.RegisterType(c.MemcachedLockDescriptor, memlock.NewMemcachedLock)
 	return &c
 }
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"encoding"
+	"reflect"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+// BinaryCacheCodec serializes cached values that implement
+// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler interfaces.
+// The value type can be a struct with pointer receivers or a pointer to it.
+type BinaryCacheCodec[T any] struct{}
+
+// NewBinaryCacheCodec creates a new instance of the codec.
+func NewBinaryCacheCodec[T any]() *BinaryCacheCodec[T] {
+	return &BinaryCacheCodec[T]{}
+}
+
+// Id returns a unique codec identifier.
+func (c *BinaryCacheCodec[T]) Id() uint32 {
+	return CodecBinary
+}
+
+// Name returns a codec name.
+func (c *BinaryCacheCodec[T]) Name() string {
+	return "binary"
+}
+
+// Encode serializes a value using its MarshalBinary method.
+func (c *BinaryCacheCodec[T]) Encode(value T) ([]byte, error) {
+	if marshaler, ok := any(value).(encoding.BinaryMarshaler); ok {
+		return marshaler.MarshalBinary()
+	}
+	if marshaler, ok := any(&value).(encoding.BinaryMarshaler); ok {
+		return marshaler.MarshalBinary()
+	}
+	return nil, cerr.NewBadRequestError("", "UNSUPPORTED_TYPE", "Value does not implement encoding.BinaryMarshaler")
+}
+
+// Decode deserializes a value using its UnmarshalBinary method.
+func (c *BinaryCacheCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	if unmarshaler, ok := any(&value).(encoding.BinaryUnmarshaler); ok {
+		err := unmarshaler.UnmarshalBinary(data)
+		return value, err
+	}
+
+	typ := reflect.TypeOf(&value).Elem()
+	if typ.Kind() == reflect.Ptr {
+		ptr := reflect.New(typ.Elem())
+		if unmarshaler, ok := ptr.Interface().(encoding.BinaryUnmarshaler); ok {
+			err := unmarshaler.UnmarshalBinary(data)
+			value = ptr.Interface().(T)
+			return value, err
+		}
+	}
+	return value, cerr.NewBadRequestError("", "UNSUPPORTED_TYPE", "Value does not implement encoding.BinaryUnmarshaler")
+}
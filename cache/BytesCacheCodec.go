@@ -0,0 +1,52 @@
+package cache
+
+import (
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+// BytesCacheCodec stores raw bytes or strings without serialization.
+// It supports []byte, string and interface types that hold them.
+// Values of interface types are decoded as []byte.
+type BytesCacheCodec[T any] struct{}
+
+// NewBytesCacheCodec creates a new instance of the codec.
+func NewBytesCacheCodec[T any]() *BytesCacheCodec[T] {
+	return &BytesCacheCodec[T]{}
+}
+
+// Id returns a unique codec identifier.
+func (c *BytesCacheCodec[T]) Id() uint32 {
+	return CodecBytes
+}
+
+// Name returns a codec name.
+func (c *BytesCacheCodec[T]) Name() string {
+	return "bytes"
+}
+
+// Encode returns raw bytes of the value.
+func (c *BytesCacheCodec[T]) Encode(value T) ([]byte, error) {
+	switch v := any(value).(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, cerr.NewBadRequestError("", "UNSUPPORTED_TYPE", "Bytes codec supports only []byte and string values")
+}
+
+// Decode returns the value from raw bytes.
+func (c *BytesCacheCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	switch v := any(&value).(type) {
+	case *[]byte:
+		*v = data
+	case *string:
+		*v = string(data)
+	case *any:
+		*v = data
+	default:
+		return value, cerr.NewBadRequestError("", "UNSUPPORTED_TYPE", "Bytes codec supports only []byte and string values")
+	}
+	return value, nil
+}
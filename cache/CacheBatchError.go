@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"sort"
+	"strconv"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+// CacheBatchError is returned by batch operations when some of the keys failed.
+// Other keys of the batch are processed successfully.
+type CacheBatchError struct {
+	*cerr.ApplicationError
+	// Errors of failed keys
+	Errors map[string]error
+}
+
+// NewCacheBatchError creates a new error for failed keys of a batch.
+//   - correlationId    (optional) transaction id to trace execution through call chain.
+//   - errs             errors of failed keys.
+func NewCacheBatchError(correlationId string, errs map[string]error) *CacheBatchError {
+	keys := make([]string, 0, len(errs))
+	for key := range errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var cause error
+	if len(keys) > 0 {
+		cause = errs[keys[0]]
+	}
+
+	err := cerr.NewUnknownError(correlationId, "BATCH_FAILED", "Failed to process "+strconv.Itoa(len(errs))+" keys of the batch").
+		WithDetails("keys", keys)
+	if cause != nil {
+		err = err.WithCause(cause)
+	}
+	return &CacheBatchError{
+		ApplicationError: err,
+		Errors:           errs,
+	}
+}
@@ -0,0 +1,16 @@
+package cache
+
+// CacheItem is a value with its key and expiration timeout stored by batch operations.
+type CacheItem[T any] struct {
+	Key     string
+	Value   T
+	Timeout int64
+}
+
+// NewCacheItem creates a new item to store.
+//   - key        a unique value key.
+//   - value      a value to store.
+//   - timeout    expiration timeout in milliseconds.
+func NewCacheItem[T any](key string, value T, timeout int64) CacheItem[T] {
+	return CacheItem[T]{Key: key, Value: value, Timeout: timeout}
+}
@@ -0,0 +1,23 @@
+package cache
+
+// CacheState is a state of a value read from the cache.
+type CacheState int
+
+const (
+	// CacheStateMiss means the value is not cached
+	CacheStateMiss CacheState = iota
+	// CacheStateHit means the value is cached
+	CacheStateHit
+	// CacheStateAbsent means the value is cached as known to be absent
+	CacheStateAbsent
+)
+
+// CacheResult is a value read from the cache together with its state.
+type CacheResult[T any] struct {
+	// Cached or loaded value
+	Value T
+	// State of the value in the cache. Values loaded on a miss have CacheStateMiss state
+	State CacheState
+	// True when the value is past its timeout and is being refreshed
+	Stale bool
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+)
+
+func init() {
+	// Types commonly stored in caches of interface types
+	gob.Register(time.Time{})
+	gob.Register(map[string]any{})
+	gob.Register([]any{})
+}
+
+type gobEnvelope[T any] struct {
+	Value T
+}
+
+// GobCacheCodec serializes cached values using encoding/gob.
+// Values keep their exact types. When values are stored as interfaces
+// their concrete types must be registered with gob.Register.
+type GobCacheCodec[T any] struct{}
+
+// NewGobCacheCodec creates a new instance of the codec.
+func NewGobCacheCodec[T any]() *GobCacheCodec[T] {
+	return &GobCacheCodec[T]{}
+}
+
+// Id returns a unique codec identifier.
+func (c *GobCacheCodec[T]) Id() uint32 {
+	return CodecGob
+}
+
+// Name returns a codec name.
+func (c *GobCacheCodec[T]) Name() string {
+	return "gob"
+}
+
+// Encode serializes a value using gob.
+func (c *GobCacheCodec[T]) Encode(value T) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(&gobEnvelope[T]{Value: value}); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Decode deserializes a value using gob.
+func (c *GobCacheCodec[T]) Decode(data []byte) (T, error) {
+	var envelope gobEnvelope[T]
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&envelope)
+	return envelope.Value, err
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+)
+
+// GzipCacheCompressor compresses cached values using gzip.
+type GzipCacheCompressor struct{}
+
+// NewGzipCacheCompressor creates a new instance of the compressor.
+func NewGzipCacheCompressor() *GzipCacheCompressor {
+	return &GzipCacheCompressor{}
+}
+
+// Id returns a unique compressor identifier.
+func (c *GzipCacheCompressor) Id() uint32 {
+	return CompressionGzip
+}
+
+// Name returns a compressor name.
+func (c *GzipCacheCompressor) Name() string {
+	return "gzip"
+}
+
+// Compress compresses serialized value.
+func (c *GzipCacheCompressor) Compress(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Decompress restores serialized value.
+func (c *GzipCacheCompressor) Decompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,29 @@
+package cache
+
+// Identifiers of built-in codecs stored in memcached item flags.
+const (
+	CodecJson    uint32 = 0
+	CodecGob     uint32 = 1
+	CodecMsgpack uint32 = 2
+	CodecBytes   uint32 = 3
+	CodecBinary  uint32 = 4
+)
+
+// ICacheCodec interface for codecs that serialize cached values.
+// The codec identifier is stored in memcached item flags,
+// so values are decoded with the same codec they were encoded with.
+// Identifiers from 0 to 15 are reserved for built-in codecs.
+type ICacheCodec[T any] interface {
+
+	// Id returns a unique codec identifier in the range from 0 to 255.
+	Id() uint32
+
+	// Name returns a codec name used in "options.codec" configuration parameter.
+	Name() string
+
+	// Encode serializes a value into bytes.
+	Encode(value T) ([]byte, error)
+
+	// Decode deserializes a value from bytes.
+	Decode(data []byte) (T, error)
+}
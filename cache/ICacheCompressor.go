@@ -0,0 +1,28 @@
+package cache
+
+// Identifiers of built-in compressors stored in memcached item flags.
+const (
+	CompressionNone   uint32 = 0
+	CompressionGzip   uint32 = 1
+	CompressionSnappy uint32 = 2
+	CompressionZstd   uint32 = 3
+)
+
+// ICacheCompressor interface for compressors of serialized cached values.
+// The compressor identifier is stored in memcached item flags,
+// so compressed and uncompressed items can be read side by side.
+// Identifiers from 1 to 15 are reserved for built-in compressors.
+type ICacheCompressor interface {
+
+	// Id returns a unique compressor identifier in the range from 1 to 255.
+	Id() uint32
+
+	// Name returns a compressor name used in "options.compression" configuration parameter.
+	Name() string
+
+	// Compress compresses serialized value.
+	Compress(data []byte) ([]byte, error)
+
+	// Decompress restores serialized value.
+	Decompress(data []byte) ([]byte, error)
+}
@@ -0,0 +1,16 @@
+package cache
+
+// Layout of memcached item flags used by MemcachedCache.
+const (
+	// Bits 0-7 keep identifier of the codec used to serialize the value
+	flagsCodecMask uint32 = 0x000000FF
+	// Bits 8-15 keep identifier of the compressor, 0 for uncompressed values
+	flagsCompressionMask  uint32 = 0x0000FF00
+	flagsCompressionShift        = 8
+	// Bit 16 marks a manifest of the value split into chunks
+	flagsChunked uint32 = 0x00010000
+	// Bit 17 marks values prefixed with metadata of read-through methods
+	flagsMeta uint32 = 0x00020000
+	// Bit 18 marks empty items that cache values known to be absent
+	flagsAbsent uint32 = 0x00040000
+)
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"time"
+)
+
+const (
+	itemMetaVersion byte = 1
+	itemMetaSize         = 17
+)
+
+// itemMeta is metadata stored in front of values written by read-through methods.
+// It is marked by flagsMeta item flag.
+type itemMeta struct {
+	// Time in milliseconds it took to compute the value
+	delta int64
+	// Expiration time of the value in Unix milliseconds
+	expiry int64
+}
+
+func newItemMeta(delta time.Duration, timeout int64) *itemMeta {
+	return &itemMeta{
+		delta:  delta.Milliseconds(),
+		expiry: time.Now().UnixMilli() + timeout,
+	}
+}
+
+func (m *itemMeta) wrap(data []byte) []byte {
+	result := make([]byte, itemMetaSize+len(data))
+	result[0] = itemMetaVersion
+	binary.BigEndian.PutUint64(result[1:9], uint64(m.delta))
+	binary.BigEndian.PutUint64(result[9:17], uint64(m.expiry))
+	copy(result[itemMetaSize:], data)
+	return result
+}
+
+func unwrapItemMeta(data []byte) (*itemMeta, []byte, bool) {
+	if len(data) < itemMetaSize || data[0] != itemMetaVersion {
+		return nil, nil, false
+	}
+	meta := &itemMeta{
+		delta:  int64(binary.BigEndian.Uint64(data[1:9])),
+		expiry: int64(binary.BigEndian.Uint64(data[9:17])),
+	}
+	return meta, data[itemMetaSize:], true
+}
+
+// isExpired checks if the value is past its expiration and is stale.
+func (m *itemMeta) isExpired(now time.Time) bool {
+	return now.UnixMilli() >= m.expiry
+}
+
+// shouldRecompute decides if the value shall be recomputed before it expires using XFetch algorithm.
+// The probability grows as the expiration approaches, and it is higher for values that take longer
+// to compute. Beta above 1 favors earlier recomputation.
+func (m *itemMeta) shouldRecompute(beta float64, now time.Time) bool {
+	gap := -float64(m.delta) * beta * math.Log(rand.Float64())
+	return float64(now.UnixMilli())+gap >= float64(m.expiry)
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	cconv "github.com/pip-services3-gox/pip-services3-commons-gox/convert"
+)
+
+// JsonCacheCodec serializes cached values into JSON.
+// It is the default codec and it is used for items stored without codec flags.
+type JsonCacheCodec[T any] struct {
+	convertor cconv.IJSONEngine[T]
+}
+
+// NewJsonCacheCodec creates a new instance of the codec.
+func NewJsonCacheCodec[T any]() *JsonCacheCodec[T] {
+	return &JsonCacheCodec[T]{
+		convertor: cconv.NewDefaultCustomTypeJsonConvertor[T](),
+	}
+}
+
+// Id returns a unique codec identifier.
+func (c *JsonCacheCodec[T]) Id() uint32 {
+	return CodecJson
+}
+
+// Name returns a codec name.
+func (c *JsonCacheCodec[T]) Name() string {
+	return "json"
+}
+
+// Encode serializes a value into JSON.
+func (c *JsonCacheCodec[T]) Encode(value T) ([]byte, error) {
+	json, err := c.convertor.ToJson(value)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(json), nil
+}
+
+// Decode deserializes a value from JSON.
+func (c *JsonCacheCodec[T]) Decode(data []byte) (T, error) {
+	return c.convertor.FromJson(string(data))
+}
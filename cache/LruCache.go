@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lruEntry is a value kept in the in-process tier together with its state and expiration.
+// The version stamp of the value and the time it was last checked are kept for coherence checks.
+type lruEntry[T any] struct {
+	key     string
+	value   T
+	state   CacheState
+	expires time.Time
+	stamp   string
+	checked time.Time
+}
+
+// lruCache is a bounded in-process cache that evicts least recently used entries
+// and expires entries after a fixed timeout.
+type lruCache[T any] struct {
+	lock     sync.Mutex
+	maxSize  int
+	timeout  time.Duration
+	entries  map[string]*list.Element
+	order    *list.List
+	sequence uint64
+}
+
+func newLruCache[T any](maxSize int, timeout time.Duration) *lruCache[T] {
+	return &lruCache[T]{
+		maxSize: maxSize,
+		timeout: timeout,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// get returns a copy of an entry, or false when the key is missing or expired.
+func (c *lruCache[T]) get(key string) (lruEntry[T], bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return lruEntry[T]{}, false
+	}
+	entry := element.Value.(*lruEntry[T])
+	if !time.Now().Before(entry.expires) {
+		c.removeElement(element)
+		return lruEntry[T]{}, false
+	}
+	c.order.MoveToFront(element)
+	return *entry, true
+}
+
+// version returns the invalidation sequence to pass to put.
+func (c *lruCache[T]) version() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.sequence
+}
+
+// put keeps a value with its version stamp read at the given invalidation sequence.
+// The value is dropped when anything was invalidated after it was read,
+// so values read before a concurrent write do not outlive the write.
+func (c *lruCache[T]) put(key string, value T, state CacheState, stamp string, sequence uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if sequence != c.sequence {
+		return
+	}
+	now := time.Now()
+	entry := &lruEntry[T]{key: key, value: value, state: state, expires: now.Add(c.timeout), stamp: stamp, checked: now}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxSize {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// verify keeps an entry if its version stamp is not changed and evicts it otherwise.
+// Returns true if the entry is kept.
+func (c *lruCache[T]) verify(key string, stamp string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	entry := element.Value.(*lruEntry[T])
+	if entry.stamp != stamp {
+		c.removeElement(element)
+		return false
+	}
+	entry.checked = time.Now()
+	return true
+}
+
+// remove invalidates values of the keys.
+func (c *lruCache[T]) remove(keys ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sequence++
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.removeElement(element)
+		}
+	}
+}
+
+// removePrefix invalidates values of all keys that start with the prefix.
+func (c *lruCache[T]) removePrefix(prefix string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sequence++
+	for key, element := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.removeElement(element)
+		}
+	}
+}
+
+// clear invalidates all values.
+func (c *lruCache[T]) clear() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sequence++
+	c.entries = map[string]*list.Element{}
+	c.order.Init()
+}
+
+func (c *lruCache[T]) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*lruEntry[T]).key)
+}
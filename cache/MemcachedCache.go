@@ -2,22 +2,79 @@ package cache
 
 import (
 	"context"
-	"strconv"
-	"time"
+	"strings"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
-	cconv "github.com/pip-services3-gox/pip-services3-commons-gox/convert"
 	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
 	cref "github.com/pip-services3-gox/pip-services3-commons-gox/refer"
-	ccon "github.com/pip-services3-gox/pip-services3-components-gox/connect"
 	clog "github.com/pip-services3-gox/pip-services3-components-gox/log"
+	memcon "github.com/pip-services3-gox/pip-services3-memcached-gox/connect"
 )
 
 /*
 MemcachedCache are distributed cache that stores values in Memcaches caching service.
 
-The current implementation does not support authentication.
+Connections with "tls" protocol are encrypted using TLS. When credentials are configured
+every connection is authenticated using text protocol authentication,
+see MemcachedConnection for details.
+
+Values are serialized by a codec selected with "options.codec" or set by SetCodec method.
+The codec identifier is kept in item flags, so values are always decoded by the codec they were stored with.
+Json and msgpack codecs restore exact types only for concrete value types, with interface types like any
+values are decoded into generic maps, slices and numbers. Only gob and binary codecs keep values of interface types intact.
+Values larger than the compression threshold are compressed, and the compressor is marked in item flags too,
+so compressed and uncompressed items stay readable after the configuration is changed.
+
+With chunking enabled large values are stored in several chunk items followed by a manifest item
+under the original key. When any chunk is evicted or was not written, the value is treated as missing
+and the incomplete chunk set is removed. Chunks of overwritten values are not deleted and expire with their timeout.
+
+RetrieveMany, StoreMany and RemoveMany methods group keys by servers and process the groups concurrently,
+values are retrieved with a single request to each server. Failures of some keys do not fail the whole batch,
+they are reported by CacheBatchError.
+
+Add and Replace methods store values only if the key is missing or exists respectively,
+and Append and Prepend methods concatenate raw values stored with the "bytes" codec.
+
+Touch method extends expiration of a value without rewriting it, and RetrieveAndTouch method
+reads a value and extends its expiration in one round trip with "gat" command. With sliding expiration enabled
+every Retrieve call works as RetrieveAndTouch with "options.sliding_timeout", so values expire after inactivity.
+Markers of absent values are not extended by sliding expiration and expire after "options.negative_ttl".
+
+GetOrLoad method reads a value through the cache: on a miss it calls a loader and stores the result.
+Concurrent calls for the same key within the process share a single loader call, and loader errors are not cached.
+With leases enabled a client that misses a value adds a lease item for the key, and only the lease holder
+calls the loader. Other clients poll the cache for the value up to "options.lease_wait" and then load it themselves.
+With early recomputation enabled GetOrLoad stores the loading time and expiration together with the value,
+and refreshes the value in background before it expires with a probability that grows as the expiration approaches
+(XFetch algorithm). The current value is returned while it is being refreshed.
+With stale timeout set the loaded values have soft timeout passed to GetOrLoad and hard timeout extended
+by "options.stale_timeout". Between them GetOrLoad returns the stale value and refreshes it in background,
+and the stale value is served while refreshes fail. A failed refresh is retried after "options.refresh_retry",
+so a failing loader is not called on every request. GetOrLoadWithState method tells if the value is stale.
+
+With "options.negative_ttl" set values known to be absent are cached as marker items for that time
+by StoreAbsent method, or by GetOrLoad when the loader returns NotFoundError. Negative caching is disabled by default.
+RetrieveWithState method tells hits, misses and absent values apart, while Retrieve returns absent values as missing.
+
+RetrieveWithCas and CompareAndSwap methods implement optimistic concurrency with CAS tokens,
+and Update method retries read-modify-write cycles on conflicts up to "options.cas_retries" times.
+
+Increment and Decrement methods atomically change counters stored as decimal numbers.
+Counters are readable by Retrieve method with the "json" codec.
+
+With namespaces enabled the part of a key before namespace separator is its namespace, e.g. "product" in "product:123".
+A generation counter of the namespace is folded into stored keys, and ClearNamespace increments the counter
+to make all values of the namespace unreachable in one round trip. Each operation on a namespaced key
+reads the counter first.
+
+With "options.replicas" above 1 every value is kept on that number of distinct servers picked by the server selector,
+so values of a dead server are still found on other servers. Reads try replicas in order of preference
+until one of them hits. Store, Remove, Touch and other unconditional writes are sent to all replicas concurrently
+and succeed when "options.quorum" replicas are written. Add, Replace, CompareAndSwap, Append and Prepend
+are decided by the primary replica, that is the first replica on a live server, and their results are copied
+to other replicas. Counters, leases and namespace generations are not replicated and stay on the server of their key.
 
 Configuration parameters:
 
@@ -26,22 +83,61 @@ Configuration parameters:
    - host:                  host name or IP address
    - port:                  port number
    - uri:                   resource URI or connection string with all parameters in it
+   - protocol:              (optional) "tls" to encrypt the connection
+   - weight:                (optional) relative share of keys stored on the server with ketama and rendezvous distribution (default: 1)
+ - credential(s):
+   - store_key:             (optional) a key to retrieve the credentials from ICredentialStore
+   - username:              user name
+   - password:              user password
  - options:
-   - max_size:              maximum number of values stored in this cache (default: 1000)
+   - codec:                 value codec: "json", "gob", "msgpack", "bytes" or "binary" (default: json)
+   - compression:           value compression: "none", "gzip", "snappy" or "zstd" (default: none)
+   - compression_threshold: minimum size of serialized value in bytes to compress it (default: 1024)
+   - chunking:              split values larger than chunk size into several items (default: false)
+   - chunk_size:            maximum size of a chunk in bytes (default: max_value - 1024)
+   - namespaces:            version keys by generation of their namespace to support ClearNamespace (default: false)
+   - namespace_separator:   separator between a namespace and the rest of a key (default: ":")
+   - cas_retries:           number of retries of Update method on concurrent changes (default: 10)
+   - sliding_expiration:    extend expiration of values on every Retrieve call (default: false)
+   - sliding_timeout:       expiration timeout in milliseconds set by Retrieve with sliding expiration (default: 15 min)
+   - leases:                let only one client load a missing value in GetOrLoad (default: false)
+   - lease_timeout:         time in milliseconds after which a lease of a crashed client expires (default: 5 sec)
+   - lease_wait:            time in milliseconds to wait for a value loaded by the lease holder (default: 1 sec)
+   - early_recompute:       recompute values in GetOrLoad before they expire (default: false)
+   - early_recompute_beta:  factor of early recomputation, values above 1 favor earlier recomputation (default: 1.0)
+   - stale_timeout:         time in milliseconds values loaded by GetOrLoad are served stale after their timeout, 0 to disable (default: 0)
+   - refresh_retry:         time in milliseconds before a failed background refresh is retried (default: 1 sec)
+   - negative_ttl:          time in milliseconds values are cached as absent, 0 to disable negative caching (default: 0)
+   - replicas:              number of distinct servers that keep each value (default: 1)
+   - quorum:                number of replicas that shall be written for a write to succeed (default: 1)
+   - ssl_ca_file:           (optional) CA bundle file to verify server certificates
+   - ssl_cert_file:         (optional) client certificate file
+   - ssl_key_file:          (optional) client private key file
+   - ssl_server_name:       (optional) server name to verify, by default the connection host is used
+   - ssl_insecure_skip_verify: skip verification of server certificates (default: false)
+   - key_prefix:            (optional) prefix added to all keys, for instance "myservice:"
+   - key_normalizer:        key policy: "default" to escape illegal characters and hash long keys, "none" to keep keys unchanged (default: default)
    - max_key_size:          maximum key length (default: 250)
-   - max_expiration:        maximum expiration duration in milliseconds (default: 2592000)
+   - max_expiration:        maximum expiration duration in seconds up to 2147483647, longer timeouts are reduced to it (default: 2592000)
+   - allow_no_expiration:   accept timeout 0 to keep items until they are removed (default: false)
    - max_value:             maximum value length (default: 1048576)
-   - pool_size:             pool size (default: 5)
-   - reconnect:             reconnection timeout in milliseconds (default: 10 sec)
-   - retries:               number of retries (default: 3)
-   - timeout:               default caching timeout in milliseconds (default: 1 minute)
-     - failures:              number of failures before stop retrying (default: 5)
-     - retry:                 retry timeout in milliseconds (default: 30 sec)
-     - idle:                  idle timeout before disconnect in milliseconds (default: 5 sec)
+   - pool_size:             maximum number of idle connections kept per server (default: 5)
+   - reconnect:             timeout to establish a connection in milliseconds (default: 10 sec)
+   - timeout:               socket read/write timeout in milliseconds (default: 5 sec)
+   - retries:               number of retries of an operation failed on a network error, commands that are not idempotent are retried only when the connection failed (default: 3)
+   - failures:              number of consecutive failures before a server is marked as dead (default: 5)
+   - retry:                 time a dead server stays out of service in milliseconds (default: 30 sec)
+   - remove:                redistribute keys of dead servers to live ones (default: false)
+   - distribution:          key distribution between servers: "modulo", "ketama" or "rendezvous" (default: modulo)
+   - idle:                  idle timeout before a pooled connection is reestablished in milliseconds, 0 to disable (default: 5 sec)
+
+Invalid option values are reported as ConfigError when the cache is opened.
 
 References:
 
-- *:discovery:*:*:1.0    (optional) IDiscovery services to resolve connection
+- *:discovery:*:*:1.0         (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0  (optional) Credential stores to resolve credentials
+- *:server-selector:*:*:1.0   (optional) IServerSelector to distribute keys between servers instead of options.distribution
 
 Example:
 	ctx := context.Background()
@@ -67,42 +163,77 @@ Example:
 
 */
 type MemcachedCache[T any] struct {
-	connectionResolver *ccon.ConnectionResolver
-	// maxKeySize         int
-	// maxExpiration      int64
-	// maxValue           int64
-	// poolSize           int
-	// reconnect          int
-	timeout int
-	// retries            int
-	// failures           int
-	// retry              int
-	remove bool
-	//idle   int
-	client    *memcache.Client
-	convertor cconv.IJSONEngine[T]
-	logger    clog.CompositeLogger
+	connection           *memcon.MemcachedConnection
+	codecName            string
+	codec                ICacheCodec[T]
+	codecs               map[uint32]ICacheCodec[T]
+	compressionName      string
+	compressionThreshold int
+	compressor           ICacheCompressor
+	compressors          map[uint32]ICacheCompressor
+	chunking             bool
+	chunkSize            int
+	namespaces           bool
+	namespaceSeparator   string
+	casRetries           int
+	slidingExpiration    bool
+	slidingTimeout       int64
+	loads                *loadGroup[T]
+	refreshes            *loadGroup[T]
+	leases               bool
+	leaseTimeout         int64
+	leaseWait            int64
+	earlyRecompute       bool
+	earlyRecomputeBeta   float64
+	staleTimeout         int64
+	refreshRetry         int64
+	negativeTtl          int64
+	replicas             int
+	quorum               int
+	logger               clog.CompositeLogger
+	// Called after loads write values, set by components built on top of the cache
+	written func(ctx context.Context, correlationId string, key string)
 }
 
 // NewMemcachedCache method are creates a new instance of this cache.
 func NewMemcachedCache[T any]() *MemcachedCache[T] {
 	c := &MemcachedCache[T]{
-		connectionResolver: ccon.NewEmptyConnectionResolver(),
-		// maxKeySize:         250,
-		// maxExpiration:      2592000,
-		// maxValue:           1048576,
-		// poolSize:           5,
-		// reconnect:          10000,
-		timeout: 5000,
-		// retries:            5,
-		// failures:           5,
-		// retry:              30000,
-		remove: false,
-		//idle:   5000,
-		client:    nil,
-		convertor: cconv.NewDefaultCustomTypeJsonConvertor[T](),
-		logger:    *clog.NewCompositeLogger(),
+		connection:           memcon.NewMemcachedConnection(),
+		codecName:            "json",
+		codecs:               map[uint32]ICacheCodec[T]{},
+		compressionName:      "none",
+		compressionThreshold: 1024,
+		compressors:          map[uint32]ICacheCompressor{},
+		chunking:             false,
+		chunkSize:            0,
+		namespaces:           false,
+		namespaceSeparator:   ":",
+		casRetries:           10,
+		slidingExpiration:    false,
+		slidingTimeout:       900000,
+		loads:                newLoadGroup[T](),
+		refreshes:            newLoadGroup[T](),
+		leases:               false,
+		leaseTimeout:         5000,
+		leaseWait:            1000,
+		earlyRecompute:       false,
+		earlyRecomputeBeta:   1.0,
+		staleTimeout:         0,
+		refreshRetry:         1000,
+		negativeTtl:          0,
+		replicas:             1,
+		quorum:               1,
+		logger:               *clog.NewCompositeLogger(),
 	}
+	c.AddCodec(NewJsonCacheCodec[T]())
+	c.AddCodec(NewGobCacheCodec[T]())
+	c.AddCodec(NewMsgpackCacheCodec[T]())
+	c.AddCodec(NewBytesCacheCodec[T]())
+	c.AddCodec(NewBinaryCacheCodec[T]())
+	c.codec = c.codecs[CodecJson]
+	c.AddCompressor(NewGzipCacheCompressor())
+	c.AddCompressor(NewSnappyCacheCompressor())
+	c.AddCompressor(NewZstdCacheCompressor())
 	return c
 }
 
@@ -110,34 +241,65 @@ func NewMemcachedCache[T any]() *MemcachedCache[T] {
 // 	 - ctx context.Context
 //   - config    configuration parameters to be set.
 func (c *MemcachedCache[T]) Configure(ctx context.Context, config *cconf.ConfigParams) {
-	c.connectionResolver.Configure(ctx, config)
+	c.connection.Configure(ctx, config)
 	c.logger.Configure(ctx, config)
 
-	// c.maxKeySize = config.GetAsIntegerWithDefault("options.max_key_size", c.maxKeySize)
-	// c.maxExpiration = config.GetAsLongWithDefault("options.max_expiration", c.maxExpiration)
-	// c.maxValue = config.GetAsLongWithDefault("options.max_value", c.maxValue)
-	// c.poolSize = config.GetAsIntegerWithDefault("options.pool_size", c.poolSize)
-	// c.reconnect = config.GetAsIntegerWithDefault("options.reconnect", c.reconnect)
-	c.timeout = config.GetAsIntegerWithDefault("options.timeout", c.timeout)
-	// c.retries = config.GetAsIntegerWithDefault("options.retries", c.retries)
-	// c.failures = config.GetAsIntegerWithDefault("options.failures", c.failures)
-	// c.retry = config.GetAsIntegerWithDefault("options.retry", c.retry)
-	// c.remove = config.GetAsBooleanWithDefault("options.remove", c.remove)
-	//c.idle = config.GetAsIntegerWithDefault("options.idle", c.idle)
+	c.codecName = config.GetAsStringWithDefault("options.codec", c.codecName)
+	c.compressionName = config.GetAsStringWithDefault("options.compression", c.compressionName)
+	c.compressionThreshold = config.GetAsIntegerWithDefault("options.compression_threshold", c.compressionThreshold)
+	c.chunking = config.GetAsBooleanWithDefault("options.chunking", c.chunking)
+	c.chunkSize = config.GetAsIntegerWithDefault("options.chunk_size", c.chunkSize)
+	c.namespaces = config.GetAsBooleanWithDefault("options.namespaces", c.namespaces)
+	c.namespaceSeparator = config.GetAsStringWithDefault("options.namespace_separator", c.namespaceSeparator)
+	c.casRetries = config.GetAsIntegerWithDefault("options.cas_retries", c.casRetries)
+	c.slidingExpiration = config.GetAsBooleanWithDefault("options.sliding_expiration", c.slidingExpiration)
+	c.slidingTimeout = config.GetAsLongWithDefault("options.sliding_timeout", c.slidingTimeout)
+	c.leases = config.GetAsBooleanWithDefault("options.leases", c.leases)
+	c.leaseTimeout = config.GetAsLongWithDefault("options.lease_timeout", c.leaseTimeout)
+	c.leaseWait = config.GetAsLongWithDefault("options.lease_wait", c.leaseWait)
+	c.earlyRecompute = config.GetAsBooleanWithDefault("options.early_recompute", c.earlyRecompute)
+	c.earlyRecomputeBeta = config.GetAsDoubleWithDefault("options.early_recompute_beta", c.earlyRecomputeBeta)
+	c.staleTimeout = config.GetAsLongWithDefault("options.stale_timeout", c.staleTimeout)
+	c.refreshRetry = config.GetAsLongWithDefault("options.refresh_retry", c.refreshRetry)
+	c.negativeTtl = config.GetAsLongWithDefault("options.negative_ttl", c.negativeTtl)
+	c.replicas = config.GetAsIntegerWithDefault("options.replicas", c.replicas)
+	c.quorum = config.GetAsIntegerWithDefault("options.quorum", c.quorum)
+}
+
+// AddCodec method are registers a codec to decode values stored with its identifier.
+// Registered codecs can be selected by their names in "options.codec" parameter.
+//   - codec    a codec to register.
+func (c *MemcachedCache[T]) AddCodec(codec ICacheCodec[T]) {
+	c.codecs[codec.Id()&flagsCodecMask] = codec
+}
+
+// AddCompressor method are registers a compressor to decompress values stored with its identifier.
+// Registered compressors can be selected by their names in "options.compression" parameter.
+//   - compressor    a compressor to register.
+func (c *MemcachedCache[T]) AddCompressor(compressor ICacheCompressor) {
+	c.compressors[compressor.Id()&0xFF] = compressor
+}
+
+// SetCodec method are registers a codec and uses it to encode stored values.
+//   - codec    a codec to use.
+func (c *MemcachedCache[T]) SetCodec(codec ICacheCodec[T]) {
+	c.AddCodec(codec)
+	c.codec = codec
+	c.codecName = codec.Name()
 }
 
 // SetReferences are sets references to dependent components.
 // 	 - ctx context.Context
 //   - references 	references to locate the component dependencies.
 func (c *MemcachedCache[T]) SetReferences(ctx context.Context, references cref.IReferences) {
-	c.connectionResolver.SetReferences(ctx, references)
+	c.connection.SetReferences(ctx, references)
 	c.logger.SetReferences(ctx, references)
 }
 
 // IsOpen Checks if the component is opened.
 // Returns true if the component has been opened and false otherwise.
 func (c *MemcachedCache[T]) IsOpen() bool {
-	return c.client != nil
+	return c.connection.IsOpen()
 }
 
 // Open method are opens the component.
@@ -146,45 +308,105 @@ func (c *MemcachedCache[T]) IsOpen() bool {
 //   - correlationId 	(optional) transaction id to trace execution through call chain.
 // Retruns: error or nil no errors occured.
 func (c *MemcachedCache[T]) Open(ctx context.Context, correlationId string) error {
-	connections, err := c.connectionResolver.ResolveAll(correlationId)
-
-	if err == nil && len(connections) == 0 {
-		err = cerr.NewConfigError(correlationId, "NO_CONNECTION", "Connection is not configured")
+	codec := c.findCodec(c.codecName)
+	if codec == nil {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.codec has invalid value "+c.codecName).
+			WithDetails("option", "options.codec").
+			WithDetails("value", c.codecName)
 	}
-
-	if err != nil {
-		return err
+	c.codec = codec
+
+	c.compressor = nil
+	if !strings.EqualFold(c.compressionName, "none") && c.compressionName != "" {
+		c.compressor = c.findCompressor(c.compressionName)
+		if c.compressor == nil {
+			return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.compression has invalid value "+c.compressionName).
+				WithDetails("option", "options.compression").
+				WithDetails("value", c.compressionName)
+		}
+	}
+	if c.compressionThreshold < 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.compression_threshold can not be negative").
+			WithDetails("option", "options.compression_threshold").
+			WithDetails("value", c.compressionThreshold)
 	}
 
-	var servers []string = make([]string, 0)
-	for _, connection := range connections {
-		host := connection.Host()
-		port := connection.Port()
-		if port == 0 {
-			port = 11211
+	if c.chunkSize <= 0 {
+		// Leave room for item header and key within the memcached item size limit
+		c.chunkSize = c.connection.MaxValue() - 1024
+		if c.chunkSize <= 0 {
+			c.chunkSize = c.connection.MaxValue()
 		}
-
-		servers = append(servers, host+":"+strconv.FormatInt(int64(port), 10))
+	}
+	if c.slidingExpiration && c.slidingTimeout <= 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.sliding_timeout shall be positive").
+			WithDetails("option", "options.sliding_timeout").
+			WithDetails("value", c.slidingTimeout)
+	}
+	if c.leases && (c.leaseTimeout <= 0 || c.leaseWait < 0) {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Options options.lease_timeout and options.lease_wait shall be positive").
+			WithDetails("option", "options.lease_timeout").
+			WithDetails("value", c.leaseTimeout)
+	}
+	if c.earlyRecompute && c.earlyRecomputeBeta <= 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.early_recompute_beta shall be positive").
+			WithDetails("option", "options.early_recompute_beta").
+			WithDetails("value", c.earlyRecomputeBeta)
+	}
+	if c.negativeTtl < 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.negative_ttl can not be negative").
+			WithDetails("option", "options.negative_ttl").
+			WithDetails("value", c.negativeTtl)
+	}
+	if c.staleTimeout < 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.stale_timeout can not be negative").
+			WithDetails("option", "options.stale_timeout").
+			WithDetails("value", c.staleTimeout)
+	}
+	if c.casRetries < 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.cas_retries can not be negative").
+			WithDetails("option", "options.cas_retries").
+			WithDetails("value", c.casRetries)
+	}
+	if c.namespaces && c.namespaceSeparator == "" {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.namespace_separator can not be empty").
+			WithDetails("option", "options.namespace_separator").
+			WithDetails("value", c.namespaceSeparator)
+	}
+	if c.replicas < 1 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.replicas shall be positive").
+			WithDetails("option", "options.replicas").
+			WithDetails("value", c.replicas)
+	}
+	if c.quorum < 1 || c.quorum > c.replicas {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.quorum shall be between 1 and options.replicas").
+			WithDetails("option", "options.quorum").
+			WithDetails("value", c.quorum)
+	}
+	if c.chunkSize > c.connection.MaxValue() {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.chunk_size can not exceed options.max_value").
+			WithDetails("option", "options.chunk_size").
+			WithDetails("value", c.chunkSize)
 	}
 
-	// options = {
-	//     maxKeySize: c.maxKeySize,
-	//     maxExpiration: c.maxExpiration,
-	//     maxValue: c.maxValue,
-	//     poolSize: c.poolSize,
-	//     reconnect: c.reconnect,
-	//     timeout: c.timeout,
-	//     retries: c.retries,
-	//     failures: c.failures,
-	//     retry: c.retry,
-	//     remove: c.remove,
-	//     idle: c.idle
-	// };
+	return c.connection.Open(ctx, correlationId)
+}
 
-	c.client = memcache.New(servers...)
-	c.client.Timeout = time.Duration(c.timeout) * time.Millisecond
-	//c.client.MaxIdleConns = c.idle
+func (c *MemcachedCache[T]) findCompressor(name string) ICacheCompressor {
+	for _, compressor := range c.compressors {
+		if strings.EqualFold(compressor.Name(), name) {
+			return compressor
+		}
+	}
+	return nil
+}
 
+func (c *MemcachedCache[T]) findCodec(name string) ICacheCodec[T] {
+	for _, codec := range c.codecs {
+		if strings.EqualFold(codec.Name(), name) {
+			return codec
+		}
+	}
 	return nil
 }
 
@@ -194,8 +416,7 @@ func (c *MemcachedCache[T]) Open(ctx context.Context, correlationId string) erro
 //   - correlationId 	(optional) transaction id to trace execution through call chain.
 // Retruns: error or nil no errors occured.
 func (c *MemcachedCache[T]) Close(ctx context.Context, correlationId string) error {
-	c.client = nil
-	return nil
+	return c.connection.Close(ctx, correlationId)
 }
 
 func (c *MemcachedCache[T]) checkOpened(correlationId string) (state bool, err error) {
@@ -220,18 +441,116 @@ func (c *MemcachedCache[T]) Retrieve(ctx context.Context, correlationId string,
 	if state, err := c.checkOpened(correlationId); !state {
 		return defaultValue, err
 	}
-	item, err := c.client.Get(key)
-	if err != nil && err == memcache.ErrCacheMiss {
-		err = nil
+	value, _, err = c.lookup(correlationId, key)
+	return value, err
+}
+
+// lookup reads and decodes a value by its user key.
+// Returns the state of the value, so cache misses can be told apart from stored zero values
+// and values known to be absent.
+func (c *MemcachedCache[T]) lookup(correlationId string, key string) (value T, state CacheState, err error) {
+	value, _, state, err = c.lookupWithMeta(correlationId, key)
+	return value, state, err
+}
+
+// lookupWithMeta reads and decodes a value by its user key together with its metadata.
+func (c *MemcachedCache[T]) lookupWithMeta(correlationId string, key string) (value T, meta *itemMeta, state CacheState, err error) {
+	var defaultValue T
+
+	key, err = c.itemKey(correlationId, key)
+	if err != nil {
+		return defaultValue, nil, CacheStateMiss, err
 	}
-	if item != nil {
-		value, err := c.convertor.FromJson(string(item.Value))
-		if err != nil {
-			return defaultValue, err
+
+	var item *memcache.Item
+	if c.slidingExpiration {
+		var expiration int32
+		if expiration, err = c.connection.Expiration(correlationId, c.slidingTimeout); err != nil {
+			return defaultValue, nil, CacheStateMiss, err
 		}
-		return value, nil
+		item, err = c.getAndTouchItem(correlationId, key, expiration)
+	} else {
+		item, err = c.getItem(correlationId, key)
+	}
+	if item == nil {
+		return defaultValue, nil, CacheStateMiss, err
+	}
+	if item.Flags&flagsAbsent != 0 {
+		if c.slidingExpiration {
+			err = c.restoreAbsent(correlationId, key, item)
+		}
+		return defaultValue, nil, CacheStateAbsent, err
+	}
+	value, meta, err = c.decodeWithMeta(correlationId, item)
+	if err != nil {
+		return defaultValue, nil, CacheStateMiss, err
+	}
+	return value, meta, CacheStateHit, nil
+}
+
+// getItem reads an item and reassembles it from chunks when needed.
+// Returns nil item without error for cache misses.
+func (c *MemcachedCache[T]) getItem(correlationId string, key string) (*memcache.Item, error) {
+	return c.getItemWith(correlationId, key, c.read)
+}
+
+// getItemWith reads an item from replicas chosen by a read function.
+func (c *MemcachedCache[T]) getItemWith(correlationId string, key string, read readFunc) (*memcache.Item, error) {
+	var item *memcache.Item
+	err := read(correlationId, key, func(client *memcache.Client) (err error) {
+		item, err = client.Get(key)
+		return err
+	})
+	if err == memcache.ErrCacheMiss {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.unchunkItem(correlationId, item)
+}
+
+// unchunkItem reassembles a chunked item, other items are returned as is.
+// Returns nil item without error when the chunk set is incomplete.
+func (c *MemcachedCache[T]) unchunkItem(correlationId string, item *memcache.Item) (*memcache.Item, error) {
+	if item.Flags&flagsChunked == 0 {
+		return item, nil
+	}
+
+	chunked, err := c.getChunks(correlationId, item)
+	if chunked == nil && err == nil {
+		// Clean up incomplete chunk set
+		c.removeChunks(correlationId, item)
+		c.write(correlationId, item.Key, func(client *memcache.Client) error {
+			return client.Delete(item.Key)
+		})
+	}
+	return chunked, err
+}
+
+// writeFunc writes an item with one of Memcached storage commands.
+type writeFunc func(client *memcache.Client, item *memcache.Item) error
+
+func setFunc(client *memcache.Client, item *memcache.Item) error {
+	return client.Set(item)
+}
+
+// setItem writes an item splitting it into chunks when it exceeds the chunk size.
+func (c *MemcachedCache[T]) setItem(correlationId string, item *memcache.Item) error {
+	return c.writeItem(correlationId, item, setFunc, nil)
+}
+
+// writeItem writes an item with a storage command splitting it into chunks when it exceeds the chunk size.
+// For chunked items the command is applied to the manifest. Conditional commands are given
+// a replicate command that copies their result to other replicas, see writeReplicas.
+func (c *MemcachedCache[T]) writeItem(correlationId string, item *memcache.Item, write writeFunc, replicate writeFunc) error {
+	if c.chunking && len(item.Value) > c.chunkSize {
+		return c.setChunks(correlationId, item, write, replicate)
 	}
-	return defaultValue, err
+	if err := c.connection.CheckValue(correlationId, item.Key, item.Value); err != nil {
+		return err
+	}
+	return c.writeReplicas(correlationId, item, write, replicate)
 }
 
 // Store method are stores value in the cache with expiration time.
@@ -248,20 +567,112 @@ func (c *MemcachedCache[T]) Store(ctx context.Context, correlationId string, key
 	if state, err := c.checkOpened(correlationId); !state {
 		return defaultValue, err
 	}
+	if err = c.store(correlationId, key, value, timeout, nil); err != nil {
+		return defaultValue, err
+	}
+	return value, nil
+}
+
+// store writes a value by its user key with optional metadata.
+func (c *MemcachedCache[T]) store(correlationId string, key string, value T, timeout int64, meta *itemMeta) error {
+	key, err := c.itemKey(correlationId, key)
+	if err != nil {
+		return err
+	}
 
-	timeoutInSec := int32(timeout) / 1000
+	expiration, err := c.connection.Expiration(correlationId, timeout)
+	if err != nil {
+		return err
+	}
+	item, err := c.encodeWithMeta(correlationId, key, value, meta)
+	if err != nil {
+		return err
+	}
+	item.Expiration = expiration
 
-	jsonVal, err := c.convertor.ToJson(value)
+	return c.setItem(correlationId, item)
+}
+
+func (c *MemcachedCache[T]) encode(correlationId string, key string, value T) (*memcache.Item, error) {
+	return c.encodeWithMeta(correlationId, key, value, nil)
+}
+
+// encodeWithMeta serializes and compresses a value, and prefixes it with metadata when it is set.
+func (c *MemcachedCache[T]) encodeWithMeta(correlationId string, key string, value T, meta *itemMeta) (*memcache.Item, error) {
+	data, err := c.codec.Encode(value)
 	if err != nil {
-		return defaultValue, err
+		return nil, err
 	}
+	flags := c.codec.Id() & flagsCodecMask
 
-	item := memcache.Item{
-		Key:        key,
-		Value:      []byte(jsonVal),
-		Expiration: timeoutInSec,
+	if c.compressor != nil && len(data) >= c.compressionThreshold {
+		compressed, err := c.compressor.Compress(data)
+		if err != nil {
+			return nil, err
+		}
+		// Keep the original value when compression does not help
+		if len(compressed) < len(data) {
+			data = compressed
+			flags |= c.compressor.Id() << flagsCompressionShift & flagsCompressionMask
+		}
 	}
-	return value, c.client.Set(&item)
+
+	if meta != nil {
+		data = meta.wrap(data)
+		flags |= flagsMeta
+	}
+
+	return &memcache.Item{
+		Key:   key,
+		Value: data,
+		Flags: flags,
+	}, nil
+}
+
+func (c *MemcachedCache[T]) decode(correlationId string, item *memcache.Item) (T, error) {
+	value, _, err := c.decodeWithMeta(correlationId, item)
+	return value, err
+}
+
+// decodeWithMeta decompresses and deserializes a value, and returns its metadata if it has any.
+func (c *MemcachedCache[T]) decodeWithMeta(correlationId string, item *memcache.Item) (T, *itemMeta, error) {
+	var defaultValue T
+
+	// Markers of absent values are read as missing values
+	if item.Flags&flagsAbsent != 0 {
+		return defaultValue, nil, nil
+	}
+
+	data := item.Value
+	var meta *itemMeta
+	if item.Flags&flagsMeta != 0 {
+		var ok bool
+		if meta, data, ok = unwrapItemMeta(data); !ok {
+			return defaultValue, nil, cerr.NewUnsupportedError(correlationId, "INVALID_METADATA", "Value of "+item.Key+" has invalid metadata").
+				WithDetails("key", item.Key)
+		}
+	}
+	if id := item.Flags & flagsCompressionMask >> flagsCompressionShift; id != CompressionNone {
+		compressor, ok := c.compressors[id]
+		if !ok {
+			return defaultValue, nil, cerr.NewUnsupportedError(correlationId, "UNKNOWN_COMPRESSION", "Value of "+item.Key+" is compressed with unknown compressor").
+				WithDetails("key", item.Key).
+				WithDetails("flags", item.Flags)
+		}
+		var err error
+		if data, err = compressor.Decompress(data); err != nil {
+			return defaultValue, nil, err
+		}
+	}
+
+	codec, ok := c.codecs[item.Flags&flagsCodecMask]
+	if !ok {
+		return defaultValue, nil, cerr.NewUnsupportedError(correlationId, "UNKNOWN_CODEC", "Value of "+item.Key+" is stored with unknown codec").
+			WithDetails("key", item.Key).
+			WithDetails("flags", item.Flags)
+	}
+	value, err := codec.Decode(data)
+	return value, meta, err
 }
 
 // Remove method are removes a value from the cache by its key.
@@ -276,12 +687,36 @@ func (c *MemcachedCache[T]) Remove(ctx context.Context, correlationId string, ke
 	if !state {
 		return err
 	}
+	key, err = c.itemKey(correlationId, key)
+	if err != nil {
+		return err
+	}
+	return c.removeItem(correlationId, key)
+}
 
-	err = c.client.Delete(key)
-	if err != nil && err == memcache.ErrCacheMiss {
-		err = nil
+// removeItem deletes an item together with its chunks.
+func (c *MemcachedCache[T]) removeItem(correlationId string, key string) (err error) {
+	if c.chunking {
+		var item *memcache.Item
+		err = c.read(correlationId, key, func(client *memcache.Client) (err error) {
+			item, err = client.Get(key)
+			return err
+		})
+		if err != nil && err != memcache.ErrCacheMiss {
+			return err
+		}
+		if item != nil && item.Flags&flagsChunked != 0 {
+			c.removeChunks(correlationId, item)
+		}
 	}
-	return err
+
+	return c.write(correlationId, key, func(client *memcache.Client) error {
+		// Replicas that miss the key are already removed
+		if err := client.Delete(key); err != memcache.ErrCacheMiss {
+			return err
+		}
+		return nil
+	})
 }
 
 // Contains check is value stores
@@ -295,10 +730,15 @@ func (c *MemcachedCache[T]) Contains(ctx context.Context, correlationId string,
 		c.logger.Error(ctx, correlationId, err, "Connection is not opened")
 		return false
 	}
-
-	if _, err := c.client.Get(key); err != nil {
+	key, err = c.itemKey(correlationId, key)
+	if err != nil {
 		return false
 	}
 
-	return true
+	var item *memcache.Item
+	err = c.read(correlationId, key, func(client *memcache.Client) (err error) {
+		item, err = client.Get(key)
+		return err
+	})
+	return err == nil && item.Flags&flagsAbsent == 0
 }
@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+// RetrieveWithState method are retrieves cached value together with its state,
+// so stored zero values and values known to be absent can be told apart from cache misses.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//  Retruns: cached value with its state or error.
+func (c *MemcachedCache[T]) RetrieveWithState(ctx context.Context, correlationId string, key string) (CacheResult[T], error) {
+	if state, err := c.checkOpened(correlationId); !state {
+		return CacheResult[T]{}, err
+	}
+	value, state, err := c.lookup(correlationId, key)
+	return CacheResult[T]{Value: value, State: state}, err
+}
+
+// StoreAbsent method are caches a marker that the value is known to be absent,
+// for instance when it is not found in a database. The marker expires after "options.negative_ttl",
+// and nothing is stored when negative caching is disabled.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+// Returns: error or nil for success
+func (c *MemcachedCache[T]) StoreAbsent(ctx context.Context, correlationId string, key string) error {
+	if state, err := c.checkOpened(correlationId); !state {
+		return err
+	}
+	if c.negativeTtl == 0 {
+		return nil
+	}
+	return c.storeAbsent(correlationId, key)
+}
+
+func (c *MemcachedCache[T]) storeAbsent(correlationId string, key string) error {
+	key, err := c.itemKey(correlationId, key)
+	if err != nil {
+		return err
+	}
+	expiration, err := c.connection.Expiration(correlationId, c.negativeTtl)
+	if err != nil {
+		return err
+	}
+
+	// The marker keeps its expiration time, so sliding reads can set it back
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, uint64(time.Now().UnixMilli()+c.negativeTtl))
+	return c.write(correlationId, key, func(client *memcache.Client) error {
+		return client.Set(&memcache.Item{
+			Key:        key,
+			Value:      value,
+			Flags:      flagsAbsent,
+			Expiration: expiration,
+		})
+	})
+}
+
+// restoreAbsent sets back the expiration of an absent marker extended by a sliding read,
+// so values are not kept absent longer than "options.negative_ttl".
+func (c *MemcachedCache[T]) restoreAbsent(correlationId string, key string, item *memcache.Item) error {
+	timeout := c.negativeTtl
+	if len(item.Value) == 8 {
+		timeout = int64(binary.BigEndian.Uint64(item.Value)) - time.Now().UnixMilli()
+	}
+	if timeout <= 0 {
+		return c.write(correlationId, key, func(client *memcache.Client) error {
+			err := client.Delete(key)
+			if err == memcache.ErrCacheMiss {
+				return nil
+			}
+			return err
+		})
+	}
+
+	expiration, err := c.connection.Expiration(correlationId, timeout)
+	if err != nil {
+		return err
+	}
+	return c.write(correlationId, key, func(client *memcache.Client) error {
+		err := client.Touch(key, expiration)
+		if err == memcache.ErrCacheMiss {
+			return nil
+		}
+		return err
+	})
+}
+
+// absentError creates an error returned by read-through methods for values known to be absent.
+func (c *MemcachedCache[T]) absentError(correlationId string, key string) error {
+	return cerr.NewNotFoundError(correlationId, "NOT_FOUND", "Value "+key+" is known to be absent").
+		WithDetails("key", key)
+}
+
+// isAbsentError checks if a loader reported the value as absent.
+func isAbsentError(err error) bool {
+	appErr, ok := err.(*cerr.ApplicationError)
+	return ok && appErr.Category == cerr.NotFound
+}
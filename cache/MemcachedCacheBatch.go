@@ -0,0 +1,212 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// batchErrors collects errors of keys processed concurrently.
+type batchErrors struct {
+	lock sync.Mutex
+	errs map[string]error
+}
+
+func (e *batchErrors) add(key string, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.errs[key] = err
+}
+
+func (e *batchErrors) result(correlationId string) error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return NewCacheBatchError(correlationId, e.errs)
+}
+
+// forEachServer groups item keys by servers and calls an action for every group concurrently.
+// Keys that can not be mapped to a server are reported as failed, or processed
+// in a separate group when their replicas are kept on other servers.
+func (c *MemcachedCache[T]) forEachServer(correlationId string, itemKeys map[string]string,
+	errs *batchErrors, action func(keys []string)) {
+
+	keys := make([]string, 0, len(itemKeys))
+	for itemKey := range itemKeys {
+		keys = append(keys, itemKey)
+	}
+
+	groups, groupErrs := c.connection.GroupKeys(correlationId, keys)
+	unmapped := []string{}
+	for itemKey, err := range groupErrs {
+		if c.replicas > 1 {
+			unmapped = append(unmapped, itemKey)
+			continue
+		}
+		errs.add(itemKeys[itemKey], err)
+	}
+	if len(unmapped) > 0 {
+		groups[""] = unmapped
+	}
+
+	var wg sync.WaitGroup
+	for _, group := range groups {
+		wg.Add(1)
+		go func(group []string) {
+			defer wg.Done()
+			action(group)
+		}(group)
+	}
+	wg.Wait()
+}
+
+// RetrieveMany method are retrieves cached values of several keys
+// in one round trip to each server.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - keys              unique value keys.
+// Retruns: found values by their keys, keys missing in the cache and CacheBatchError with errors of failed keys.
+func (c *MemcachedCache[T]) RetrieveMany(ctx context.Context, correlationId string,
+	keys []string) (values map[string]T, missing []string, err error) {
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return nil, nil, err
+	}
+
+	itemKeys, keyErrs := c.itemKeys(correlationId, keys)
+	errs := &batchErrors{errs: keyErrs}
+	values = map[string]T{}
+	var lock sync.Mutex
+
+	c.forEachServer(correlationId, itemKeys, errs, func(group []string) {
+		var items map[string]*memcache.Item
+		err := c.connection.Execute(correlationId, func(client *memcache.Client) (err error) {
+			items, err = client.GetMulti(group)
+			return err
+		})
+
+		for _, itemKey := range group {
+			key := itemKeys[itemKey]
+			item, itemErr := items[itemKey], err
+			if item != nil && itemErr == nil {
+				item, itemErr = c.unchunkItem(correlationId, item)
+			}
+			if item == nil && c.replicas > 1 {
+				// Keys missed or failed on the primary replica are read from other replicas
+				item, itemErr = c.getItem(correlationId, itemKey)
+			}
+			if itemErr != nil {
+				errs.add(key, itemErr)
+				continue
+			}
+			if item == nil || item.Flags&flagsAbsent != 0 {
+				continue
+			}
+			value, err := c.decode(correlationId, item)
+			if err != nil {
+				errs.add(key, err)
+				continue
+			}
+			lock.Lock()
+			values[key] = value
+			lock.Unlock()
+		}
+	})
+
+	missing = []string{}
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if _, ok := errs.errs[key]; ok {
+			continue
+		}
+		missing = append(missing, key)
+	}
+	return values, uniqueKeys(missing), errs.result(correlationId)
+}
+
+// StoreMany method are stores several values in the cache, each with its own expiration time.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - items             items to store.
+// Returns: CacheBatchError with errors of failed keys or nil for success
+func (c *MemcachedCache[T]) StoreMany(ctx context.Context, correlationId string, items []CacheItem[T]) error {
+	if state, err := c.checkOpened(correlationId); !state {
+		return err
+	}
+
+	keys := make([]string, len(items))
+	for index, item := range items {
+		keys[index] = item.Key
+	}
+	itemKeys, keyErrs := c.itemKeys(correlationId, keys)
+	errs := &batchErrors{errs: keyErrs}
+
+	// The last item wins for duplicated keys
+	itemsByKey := map[string]CacheItem[T]{}
+	for _, item := range items {
+		itemsByKey[item.Key] = item
+	}
+
+	c.forEachServer(correlationId, itemKeys, errs, func(group []string) {
+		for _, itemKey := range group {
+			key := itemKeys[itemKey]
+			cacheItem := itemsByKey[key]
+			expiration, err := c.connection.Expiration(correlationId, cacheItem.Timeout)
+			var item *memcache.Item
+			if err == nil {
+				item, err = c.encode(correlationId, itemKey, cacheItem.Value)
+			}
+			if err == nil {
+				item.Expiration = expiration
+				err = c.setItem(correlationId, item)
+			}
+			if err != nil {
+				errs.add(key, err)
+			}
+		}
+	})
+
+	return errs.result(correlationId)
+}
+
+// RemoveMany method are removes several values from the cache by their keys.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - keys              unique value keys.
+// Retruns: CacheBatchError with errors of failed keys or nil for success
+func (c *MemcachedCache[T]) RemoveMany(ctx context.Context, correlationId string, keys []string) error {
+	if state, err := c.checkOpened(correlationId); !state {
+		return err
+	}
+
+	itemKeys, keyErrs := c.itemKeys(correlationId, keys)
+	errs := &batchErrors{errs: keyErrs}
+
+	c.forEachServer(correlationId, itemKeys, errs, func(group []string) {
+		for _, itemKey := range group {
+			if err := c.removeItem(correlationId, itemKey); err != nil {
+				errs.add(itemKeys[itemKey], err)
+			}
+		}
+	})
+
+	return errs.result(correlationId)
+}
+
+func uniqueKeys(keys []string) []string {
+	seen := map[string]bool{}
+	result := keys[:0]
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, key)
+		}
+	}
+	return result
+}
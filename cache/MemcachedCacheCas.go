@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+func casFunc(client *memcache.Client, item *memcache.Item) error {
+	return client.CompareAndSwap(item)
+}
+
+func addFunc(client *memcache.Client, item *memcache.Item) error {
+	return client.Add(item)
+}
+
+// RetrieveWithCas method are retrieves cached value together with its CAS token.
+// The token is used by CompareAndSwap method to detect concurrent modifications.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//  Retruns: cached value, its CAS token or 0 if the value is missing, and error.
+//  Values known to be absent are returned as zero values with the token to replace them.
+func (c *MemcachedCache[T]) RetrieveWithCas(ctx context.Context, correlationId string, key string) (value T, cas uint64, err error) {
+	var defaultValue T
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return defaultValue, 0, err
+	}
+	key, err = c.itemKey(correlationId, key)
+	if err != nil {
+		return defaultValue, 0, err
+	}
+	value, cas, _, err = c.retrieveWithCas(correlationId, key)
+	return value, cas, err
+}
+
+// retrieveWithCas reads a value with its CAS token. Markers of absent values
+// are returned as missing values with the token to replace them.
+// Tokens are read from the primary replica where CompareAndSwap checks them.
+func (c *MemcachedCache[T]) retrieveWithCas(correlationId string, itemKey string) (value T, cas uint64, found bool, err error) {
+	var defaultValue T
+
+	item, err := c.getItemWith(correlationId, itemKey, c.readPrimary)
+	if item == nil {
+		return defaultValue, 0, false, err
+	}
+	value, err = c.decode(correlationId, item)
+	if err != nil {
+		return defaultValue, 0, false, err
+	}
+	return value, item.CasID, item.Flags&flagsAbsent == 0, nil
+}
+
+// CompareAndSwap method are stores value in the cache only if it was not changed
+// since its CAS token was retrieved. Zero token stores the value only if it is missing.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - value             a value to store.
+//   - cas               a CAS token returned by RetrieveWithCas.
+//   - timeout           expiration timeout in milliseconds.
+// Returns: true if the value was stored, false if it was changed or removed concurrently, and error.
+func (c *MemcachedCache[T]) CompareAndSwap(ctx context.Context, correlationId string, key string, value T,
+	cas uint64, timeout int64) (bool, error) {
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return false, err
+	}
+	key, err := c.itemKey(correlationId, key)
+	if err != nil {
+		return false, err
+	}
+	return c.compareAndSwap(correlationId, key, value, cas, timeout)
+}
+
+func (c *MemcachedCache[T]) compareAndSwap(correlationId string, itemKey string, value T,
+	cas uint64, timeout int64) (bool, error) {
+
+	expiration, err := c.connection.Expiration(correlationId, timeout)
+	if err != nil {
+		return false, err
+	}
+	item, err := c.encode(correlationId, itemKey, value)
+	if err != nil {
+		return false, err
+	}
+	item.Expiration = expiration
+	item.CasID = cas
+
+	write := casFunc
+	if cas == 0 {
+		write = addFunc
+	}
+	err = c.writeItem(correlationId, item, write, setFunc)
+	if err == memcache.ErrCASConflict || err == memcache.ErrNotStored || err == memcache.ErrCacheMiss {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Update method are changes cached value using optimistic concurrency.
+// It reads the value, calls the update function and stores the result with CompareAndSwap,
+// and repeats these steps when the value was changed concurrently.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - update            a function that computes a new value from the current one.
+//                       It is called with found set to false when the value is missing.
+//   - timeout           expiration timeout in milliseconds.
+// Returns: the stored value, or ConflictError when all attempts failed on concurrent changes.
+func (c *MemcachedCache[T]) Update(ctx context.Context, correlationId string, key string,
+	update func(old T, found bool) (T, error), timeout int64) (result T, err error) {
+
+	var defaultValue T
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return defaultValue, err
+	}
+	itemKey, err := c.itemKey(correlationId, key)
+	if err != nil {
+		return defaultValue, err
+	}
+
+	for attempt := 0; attempt <= c.casRetries; attempt++ {
+		old, cas, found, err := c.retrieveWithCas(correlationId, itemKey)
+		if err != nil {
+			return defaultValue, err
+		}
+
+		value, err := update(old, found)
+		if err != nil {
+			return defaultValue, err
+		}
+
+		stored, err := c.compareAndSwap(correlationId, itemKey, value, cas, timeout)
+		if err != nil {
+			return defaultValue, err
+		}
+		if stored {
+			return value, nil
+		}
+	}
+
+	return defaultValue, cerr.NewConflictError(correlationId, "CAS_CONFLICT",
+		"Value of "+key+" was changed concurrently "+strconv.Itoa(c.casRetries+1)+" times").
+		WithDetails("key", key)
+}
@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// chunkManifest describes a value split into several chunk items.
+// The manifest is stored under the original key after all chunks are written,
+// and the unique id keeps chunks of concurrent writers apart.
+type chunkManifest struct {
+	id       string
+	count    int
+	size     int
+	checksum uint32
+}
+
+func (m *chunkManifest) String() string {
+	return fmt.Sprintf("%s %d %d %d", m.id, m.count, m.size, m.checksum)
+}
+
+func parseChunkManifest(data []byte) (*chunkManifest, bool) {
+	m := &chunkManifest{}
+	n, err := fmt.Sscanf(string(data), "%s %d %d %d", &m.id, &m.count, &m.size, &m.checksum)
+	if err != nil || n != 4 || m.count <= 0 || m.size < 0 {
+		return nil, false
+	}
+	return m, true
+}
+
+// chunkKeys returns keys of all chunks of the value stored under a given normalized key.
+func (c *MemcachedCache[T]) chunkKeys(correlationId string, key string, manifest *chunkManifest) ([]string, error) {
+	keys := make([]string, manifest.count)
+	for index := range keys {
+		chunkKey, err := c.connection.DeriveKey(correlationId, key, "#"+manifest.id+"."+strconv.Itoa(index))
+		if err != nil {
+			return nil, err
+		}
+		keys[index] = chunkKey
+	}
+	return keys, nil
+}
+
+// setChunks writes a large item as a set of chunks followed by the manifest.
+// The manifest is written by a given storage command, and the chunks are removed when it fails.
+func (c *MemcachedCache[T]) setChunks(correlationId string, item *memcache.Item, write writeFunc, replicate writeFunc) error {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+
+	manifest := &chunkManifest{
+		id:       hex.EncodeToString(id),
+		count:    (len(item.Value) + c.chunkSize - 1) / c.chunkSize,
+		size:     len(item.Value),
+		checksum: crc32.ChecksumIEEE(item.Value),
+	}
+
+	keys, err := c.chunkKeys(correlationId, item.Key, manifest)
+	if err != nil {
+		return err
+	}
+
+	chunks := make([]*memcache.Item, manifest.count)
+	for index := range chunks {
+		start := index * c.chunkSize
+		end := start + c.chunkSize
+		if end > len(item.Value) {
+			end = len(item.Value)
+		}
+		chunks[index] = &memcache.Item{
+			Key:        keys[index],
+			Value:      item.Value[start:end],
+			Expiration: item.Expiration,
+		}
+	}
+
+	for _, chunk := range chunks {
+		err := c.write(correlationId, chunk.Key, func(client *memcache.Client) error {
+			return client.Set(chunk)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	manifestItem := &memcache.Item{
+		Key:        item.Key,
+		Value:      []byte(manifest.String()),
+		Flags:      item.Flags | flagsChunked,
+		Expiration: item.Expiration,
+		CasID:      item.CasID,
+	}
+	err = c.writeReplicas(correlationId, manifestItem, write, replicate)
+	if err != nil {
+		c.removeChunks(correlationId, manifestItem)
+	}
+	return err
+}
+
+// getChunks reassembles a value from chunks referenced by the manifest item.
+// It returns nil when any chunk is missing or the value is corrupted,
+// so incomplete chunk sets are treated as cache misses.
+func (c *MemcachedCache[T]) getChunks(correlationId string, item *memcache.Item) (*memcache.Item, error) {
+	manifest, ok := parseChunkManifest(item.Value)
+	if !ok {
+		return nil, nil
+	}
+
+	keys, err := c.chunkKeys(correlationId, item.Key, manifest)
+	if err != nil {
+		return nil, nil
+	}
+
+	chunks, err := c.readChunks(correlationId, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, manifest.size)
+	for _, key := range keys {
+		chunk, ok := chunks[key]
+		if !ok {
+			return nil, nil
+		}
+		data = append(data, chunk.Value...)
+	}
+	if len(data) != manifest.size || crc32.ChecksumIEEE(data) != manifest.checksum {
+		return nil, nil
+	}
+
+	return &memcache.Item{
+		Key:        item.Key,
+		Value:      data,
+		Flags:      item.Flags &^ flagsChunked,
+		Expiration: item.Expiration,
+		CasID:      item.CasID,
+	}, nil
+}
+
+// readChunks reads chunks in one request to each server. With replicas chunks are read
+// one by one, so chunks missed on their primary replicas are read from other replicas.
+func (c *MemcachedCache[T]) readChunks(correlationId string, keys []string) (map[string]*memcache.Item, error) {
+	if c.replicas == 1 {
+		var chunks map[string]*memcache.Item
+		err := c.connection.Execute(correlationId, func(client *memcache.Client) (err error) {
+			chunks, err = client.GetMulti(keys)
+			return err
+		})
+		return chunks, err
+	}
+
+	chunks := map[string]*memcache.Item{}
+	for _, key := range keys {
+		var chunk *memcache.Item
+		err := c.read(correlationId, key, func(client *memcache.Client) (err error) {
+			chunk, err = client.Get(key)
+			return err
+		})
+		if err == memcache.ErrCacheMiss {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		chunks[key] = chunk
+	}
+	return chunks, nil
+}
+
+// removeChunks deletes chunks referenced by the manifest item.
+func (c *MemcachedCache[T]) removeChunks(correlationId string, item *memcache.Item) {
+	manifest, ok := parseChunkManifest(item.Value)
+	if !ok {
+		return
+	}
+
+	keys, _ := c.chunkKeys(correlationId, item.Key, manifest)
+	for _, key := range keys {
+		c.write(correlationId, key, func(client *memcache.Client) error {
+			return client.Delete(key)
+		})
+	}
+}
+
+// touchChunks updates expiration of chunks referenced by the manifest item.
+// Missing chunks are detected when the value is reassembled.
+func (c *MemcachedCache[T]) touchChunks(correlationId string, item *memcache.Item, expiration int32) {
+	manifest, ok := parseChunkManifest(item.Value)
+	if !ok {
+		return
+	}
+
+	keys, _ := c.chunkKeys(correlationId, item.Key, manifest)
+	for _, key := range keys {
+		c.write(correlationId, key, func(client *memcache.Client) error {
+			return client.Touch(key, expiration)
+		})
+	}
+}
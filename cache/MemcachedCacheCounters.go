@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+// NoInitialValue disables initialization of missing counters in Increment and Decrement methods.
+const NoInitialValue int64 = -1
+
+// Increment method are atomically increments a counter stored as a decimal number.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique counter key.
+//   - delta             a value to add.
+//   - initial           a value to set when the counter is missing, or NoInitialValue to fail.
+//   - timeout           expiration timeout in milliseconds of initialized counter, ignored with NoInitialValue.
+// Returns: the new counter value, NotFoundError when the counter is missing
+// or BadRequestError when the stored value is not a number.
+func (c *MemcachedCache[T]) Increment(ctx context.Context, correlationId string, key string,
+	delta uint64, initial int64, timeout int64) (uint64, error) {
+	return c.incrDecr(correlationId, key, delta, initial, timeout, true)
+}
+
+// Decrement method are atomically decrements a counter stored as a decimal number.
+// Memcached does not decrement counters below 0.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique counter key.
+//   - delta             a value to subtract.
+//   - initial           a value to set when the counter is missing, or NoInitialValue to fail.
+//   - timeout           expiration timeout in milliseconds of initialized counter, ignored with NoInitialValue.
+// Returns: the new counter value, NotFoundError when the counter is missing
+// or BadRequestError when the stored value is not a number.
+func (c *MemcachedCache[T]) Decrement(ctx context.Context, correlationId string, key string,
+	delta uint64, initial int64, timeout int64) (uint64, error) {
+	return c.incrDecr(correlationId, key, delta, initial, timeout, false)
+}
+
+func (c *MemcachedCache[T]) incrDecr(correlationId string, key string,
+	delta uint64, initial int64, timeout int64, increment bool) (uint64, error) {
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return 0, err
+	}
+	itemKey, err := c.itemKey(correlationId, key)
+	if err != nil {
+		return 0, err
+	}
+	// The timeout is used only to initialize missing counters
+	var expiration int32
+	if initial >= 0 {
+		expiration, err = c.connection.Expiration(correlationId, timeout)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for attempt := 0; attempt <= c.casRetries; attempt++ {
+		var value uint64
+		err = c.connection.ExecuteOnce(correlationId, func(client *memcache.Client) (err error) {
+			if increment {
+				value, err = client.Increment(itemKey, delta)
+			} else {
+				value, err = client.Decrement(itemKey, delta)
+			}
+			return err
+		})
+		if err == nil {
+			return value, nil
+		}
+		if err != memcache.ErrCacheMiss {
+			return 0, c.counterError(correlationId, key, err)
+		}
+
+		if initial < 0 {
+			return 0, cerr.NewNotFoundError(correlationId, "COUNTER_NOT_FOUND", "Counter "+key+" is missing").
+				WithDetails("key", key)
+		}
+
+		err = c.connection.ExecuteOnce(correlationId, func(client *memcache.Client) error {
+			return client.Add(&memcache.Item{
+				Key:        itemKey,
+				Value:      []byte(strconv.FormatInt(initial, 10)),
+				Expiration: expiration,
+			})
+		})
+		if err == nil {
+			return uint64(initial), nil
+		}
+		// Retry the operation when the counter was initialized concurrently
+		if err != memcache.ErrNotStored {
+			return 0, err
+		}
+	}
+
+	return 0, cerr.NewConflictError(correlationId, "COUNTER_CONFLICT", "Counter "+key+" was changed concurrently").
+		WithDetails("key", key)
+}
+
+func (c *MemcachedCache[T]) counterError(correlationId string, key string, err error) error {
+	if strings.Contains(err.Error(), "non-numeric") {
+		return cerr.NewBadRequestError(correlationId, "NOT_A_NUMBER", "Value of "+key+" is not a number").
+			WithDetails("key", key).
+			WithCause(err)
+	}
+	return err
+}
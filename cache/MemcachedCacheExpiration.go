@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// Touch method are updates expiration time of a cached value without reading it.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - timeout           new expiration timeout in milliseconds.
+// Returns: true if the value exists, false if it is missing, and error.
+func (c *MemcachedCache[T]) Touch(ctx context.Context, correlationId string, key string, timeout int64) (bool, error) {
+	if state, err := c.checkOpened(correlationId); !state {
+		return false, err
+	}
+	key, err := c.itemKey(correlationId, key)
+	if err != nil {
+		return false, err
+	}
+	expiration, err := c.connection.Expiration(correlationId, timeout)
+	if err != nil {
+		return false, err
+	}
+
+	// Chunks are found through the manifest, so it has to be read
+	if c.chunking {
+		item, err := c.getAndTouchItem(correlationId, key, expiration)
+		return item != nil, err
+	}
+
+	err = c.write(correlationId, key, func(client *memcache.Client) error {
+		return client.Touch(key, expiration)
+	})
+	if err == memcache.ErrCacheMiss {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RetrieveAndTouch method are retrieves cached value and updates its expiration time
+// in one round trip.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - timeout           new expiration timeout in milliseconds.
+//  Retruns: cached value or error.
+func (c *MemcachedCache[T]) RetrieveAndTouch(ctx context.Context, correlationId string, key string, timeout int64) (value T, err error) {
+	var defaultValue T
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return defaultValue, err
+	}
+	key, err = c.itemKey(correlationId, key)
+	if err != nil {
+		return defaultValue, err
+	}
+
+	expiration, err := c.connection.Expiration(correlationId, timeout)
+	if err != nil {
+		return defaultValue, err
+	}
+	item, err := c.getAndTouchItem(correlationId, key, expiration)
+	if item != nil {
+		return c.decode(correlationId, item)
+	}
+	return defaultValue, err
+}
+
+// getAndTouchItem reads an item with "gat" command and updates expiration of its chunks.
+// Returns nil item without error for cache misses.
+func (c *MemcachedCache[T]) getAndTouchItem(correlationId string, key string, expiration int32) (*memcache.Item, error) {
+	var item *memcache.Item
+	err := c.read(correlationId, key, func(client *memcache.Client) (err error) {
+		item, err = client.GetAndTouch(key, expiration)
+		return err
+	})
+	if err == memcache.ErrCacheMiss {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Flags&flagsChunked != 0 {
+		c.touchChunks(correlationId, item, expiration)
+	}
+	return c.unchunkItem(correlationId, item)
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// leaseSuffix marks lease items. The default key normalizer
+// escapes '%' in user keys, so the suffix never collides with them.
+const leaseSuffix = "%lease"
+
+// leasePollInterval is an interval to check if the lease holder stored the value.
+const leasePollInterval = 50 * time.Millisecond
+
+// loadWithLease loads a missing value only if this client acquires the lease of the key.
+// Other clients wait for the value stored by the lease holder up to the lease wait budget,
+// and load the value themselves when it does not appear in time.
+func (c *MemcachedCache[T]) loadWithLease(ctx context.Context, correlationId string, key string,
+	loader func(ctx context.Context) (T, error), timeout int64) (T, error) {
+
+	leaseKey, acquired := c.acquireLease(ctx, correlationId, key)
+	if acquired {
+		defer c.releaseLease(correlationId, leaseKey)
+		return c.load(ctx, correlationId, key, loader, timeout)
+	}
+	if leaseKey == "" {
+		return c.load(ctx, correlationId, key, loader, timeout)
+	}
+
+	deadline := time.Now().Add(time.Duration(c.leaseWait) * time.Millisecond)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			var defaultValue T
+			return defaultValue, ctx.Err()
+		case <-time.After(leasePollInterval):
+		}
+
+		value, state, err := c.lookup(correlationId, key)
+		if state == CacheStateHit {
+			return value, nil
+		}
+		if state == CacheStateAbsent {
+			return value, c.absentError(correlationId, key)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	c.logger.Debug(ctx, correlationId, "Lease of %s was not released in time, loading the value", key)
+	return c.load(ctx, correlationId, key, loader, timeout)
+}
+
+// acquireLease adds a lease item for the key.
+// Returns the lease key and true if the lease was acquired by this client,
+// or empty key when leases can not be used.
+func (c *MemcachedCache[T]) acquireLease(ctx context.Context, correlationId string, key string) (string, bool) {
+	itemKey, err := c.itemKey(correlationId, key)
+	if err == nil {
+		itemKey, err = c.connection.DeriveKey(correlationId, itemKey, leaseSuffix)
+	}
+	var expiration int32
+	if err == nil {
+		expiration, err = c.connection.Expiration(correlationId, c.leaseTimeout)
+	}
+	if err == nil {
+		err = c.connection.ExecuteOnce(correlationId, func(client *memcache.Client) error {
+			return client.Add(&memcache.Item{Key: itemKey, Value: []byte("1"), Expiration: expiration})
+		})
+	}
+
+	if err == memcache.ErrNotStored {
+		return itemKey, false
+	}
+	if err != nil {
+		c.logger.Warn(ctx, correlationId, "Failed to acquire lease of %s: %v", key, err)
+		return "", false
+	}
+	return itemKey, true
+}
+
+func (c *MemcachedCache[T]) releaseLease(correlationId string, leaseKey string) {
+	c.connection.Execute(correlationId, func(client *memcache.Client) error {
+		return client.Delete(leaseKey)
+	})
+}
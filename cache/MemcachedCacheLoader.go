@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memcon "github.com/pip-services3-gox/pip-services3-memcached-gox/connect"
+)
+
+// loadCall is a loader call shared by concurrent callers of the same key.
+type loadCall[T any] struct {
+	done  sync.WaitGroup
+	value T
+	err   error
+}
+
+// loadGroup coalesces concurrent loads of the same key within the process.
+type loadGroup[T any] struct {
+	lock  sync.Mutex
+	calls map[string]*loadCall[T]
+	// Times of failed background loads, they are not retried until the retry delay passes
+	failures map[string]time.Time
+}
+
+func newLoadGroup[T any]() *loadGroup[T] {
+	return &loadGroup[T]{
+		calls:    map[string]*loadCall[T]{},
+		failures: map[string]time.Time{},
+	}
+}
+
+// do calls the load function once for all concurrent callers of the key.
+// Returns the result of the load and true if it was shared with another caller.
+func (g *loadGroup[T]) do(key string, load func() (T, error)) (T, bool, error) {
+	g.lock.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.lock.Unlock()
+		call.done.Wait()
+		return call.value, true, call.err
+	}
+	call := &loadCall[T]{}
+	call.done.Add(1)
+	g.calls[key] = call
+	g.lock.Unlock()
+
+	g.run(key, call, load)
+	return call.value, false, call.err
+}
+
+// run calls the load function and releases the key. Panics of the load function
+// are returned as errors to all callers, so background loads do not crash the process.
+func (g *loadGroup[T]) run(key string, call *loadCall[T], load func() (T, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			var value T
+			call.value = value
+			call.err = cerr.NewInternalError("", "LOAD_FAILED", "Loader of "+key+" failed unexpectedly").
+				WithDetails("panic", fmt.Sprint(r))
+		}
+		g.lock.Lock()
+		delete(g.calls, key)
+		g.lock.Unlock()
+		call.done.Done()
+	}()
+
+	call.value, call.err = load()
+}
+
+// doAsync calls the load function in background unless the key is already being loaded,
+// or its last background load failed less than the retry delay ago.
+func (g *loadGroup[T]) doAsync(key string, retry time.Duration, load func() (T, error)) {
+	g.lock.Lock()
+	if _, ok := g.calls[key]; ok {
+		g.lock.Unlock()
+		return
+	}
+	if failed, ok := g.failures[key]; ok {
+		if time.Since(failed) < retry {
+			g.lock.Unlock()
+			return
+		}
+		delete(g.failures, key)
+	}
+	call := &loadCall[T]{}
+	call.done.Add(1)
+	g.calls[key] = call
+	g.lock.Unlock()
+
+	go func() {
+		g.run(key, call, load)
+		if call.err != nil {
+			g.lock.Lock()
+			g.failures[key] = time.Now()
+			g.lock.Unlock()
+		}
+	}()
+}
+
+// GetOrLoad method are retrieves cached value, and on a miss calls the loader and stores its result.
+// Concurrent calls for the same key within the process wait for a single loader call.
+// The loader is called with the context of the first caller. Its errors are returned
+// to all waiting callers and are not cached, and its panics are returned as InternalError.
+// When the cache is not available the loader is called and its result is not stored.
+// Only when "options.negative_ttl" is set, NotFoundError of the loader is cached as an absent value
+// for that time, and following calls return NotFoundError without calling the loader.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - loader            a function to load the value on a miss.
+//   - timeout           expiration timeout in milliseconds of loaded value.
+// Returns: cached or loaded value, or error.
+func (c *MemcachedCache[T]) GetOrLoad(ctx context.Context, correlationId string, key string,
+	loader func(ctx context.Context) (T, error), timeout int64) (T, error) {
+
+	result, err := c.GetOrLoadWithState(ctx, correlationId, key, loader, timeout)
+	return result.Value, err
+}
+
+// GetOrLoadWithState method are works as GetOrLoad and also tells if the returned value is stale.
+// With "options.stale_timeout" set values are kept after their timeout for the stale period.
+// A stale value is returned immediately and refreshed in background, and it is served
+// until the end of the stale period while refreshes fail. Failed refreshes are retried
+// after "options.refresh_retry".
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - loader            a function to load the value on a miss.
+//   - timeout           expiration timeout in milliseconds of loaded value.
+// Returns: cached or loaded value with its state, or error. Values known to be absent
+// are returned with CacheStateAbsent state and NotFoundError.
+func (c *MemcachedCache[T]) GetOrLoadWithState(ctx context.Context, correlationId string, key string,
+	loader func(ctx context.Context) (T, error), timeout int64) (CacheResult[T], error) {
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return CacheResult[T]{}, err
+	}
+
+	if _, err := c.connection.Expiration(correlationId, timeout); err != nil {
+		return CacheResult[T]{}, err
+	}
+
+	value, meta, state, err := c.lookupWithMeta(correlationId, key)
+	if state == CacheStateAbsent {
+		return CacheResult[T]{State: CacheStateAbsent}, c.absentError(correlationId, key)
+	}
+	if state == CacheStateHit {
+		now := time.Now()
+		if meta != nil && c.staleTimeout > 0 && meta.isExpired(now) {
+			c.refresh(correlationId, key, loader, timeout)
+			return CacheResult[T]{Value: value, State: CacheStateHit, Stale: true}, nil
+		}
+		if c.earlyRecompute && meta != nil && meta.shouldRecompute(c.earlyRecomputeBeta, now) {
+			c.refresh(correlationId, key, loader, timeout)
+		}
+		return CacheResult[T]{Value: value, State: CacheStateHit}, nil
+	}
+	if err != nil {
+		if appErr, ok := err.(*cerr.ApplicationError); ok && appErr.Category == cerr.BadRequest {
+			return CacheResult[T]{}, err
+		}
+		c.logger.Warn(ctx, correlationId, "Failed to retrieve %s from cache: %v", key, err)
+	}
+
+	value, _, err = c.loads.do(key, func() (T, error) {
+		if c.leases {
+			return c.loadWithLease(ctx, correlationId, key, loader, timeout)
+		}
+		return c.load(ctx, correlationId, key, loader, timeout)
+	})
+	if isAbsentError(err) {
+		return CacheResult[T]{State: CacheStateAbsent}, err
+	}
+	if err != nil {
+		return CacheResult[T]{}, err
+	}
+	return CacheResult[T]{Value: value, State: CacheStateMiss}, nil
+}
+
+// load calls the loader and stores its result.
+func (c *MemcachedCache[T]) load(ctx context.Context, correlationId string, key string,
+	loader func(ctx context.Context) (T, error), timeout int64) (T, error) {
+
+	start := time.Now()
+	value, err := loader(ctx)
+	if isAbsentError(err) && c.negativeTtl > 0 {
+		if err := c.storeAbsent(correlationId, key); err != nil {
+			c.logger.Warn(ctx, correlationId, "Failed to store absent %s in cache: %v", key, err)
+		}
+		c.notifyWritten(ctx, correlationId, key)
+	}
+	if err != nil {
+		return value, err
+	}
+
+	// Values without expiration are never stale or recomputed
+	var meta *itemMeta
+	storeTimeout := timeout
+	if timeout != memcon.NoExpiration {
+		if c.earlyRecompute || c.staleTimeout > 0 {
+			meta = newItemMeta(time.Since(start), timeout)
+		}
+		// Values are kept for the stale period after their logical expiration
+		storeTimeout += c.staleTimeout
+	}
+	if err := c.store(correlationId, key, value, storeTimeout, meta); err != nil {
+		c.logger.Warn(ctx, correlationId, "Failed to store %s in cache: %v", key, err)
+	}
+	c.notifyWritten(ctx, correlationId, key)
+	return value, nil
+}
+
+// notifyWritten tells the owning component that a value was written by a load,
+// so NearMemcachedCache invalidates it also after background refreshes.
+func (c *MemcachedCache[T]) notifyWritten(ctx context.Context, correlationId string, key string) {
+	if c.written != nil {
+		c.written(ctx, correlationId, key)
+	}
+}
+
+// refresh recomputes a value in background unless it is already being refreshed.
+// With leases enabled the value is refreshed only by the client that acquired the lease.
+// Refreshes are kept apart from loads of missing values, so callers never wait for them.
+func (c *MemcachedCache[T]) refresh(correlationId string, key string,
+	loader func(ctx context.Context) (T, error), timeout int64) {
+
+	// The refresh outlives the request that triggered it
+	ctx := context.Background()
+	c.refreshes.doAsync(key, time.Duration(c.refreshRetry)*time.Millisecond, func() (T, error) {
+		if c.leases {
+			leaseKey, acquired := c.acquireLease(ctx, correlationId, key)
+			if !acquired {
+				var defaultValue T
+				return defaultValue, nil
+			}
+			defer c.releaseLease(correlationId, leaseKey)
+		}
+
+		value, err := c.load(ctx, correlationId, key, loader, timeout)
+		if err != nil {
+			c.logger.Warn(ctx, correlationId, "Failed to refresh %s: %v", key, err)
+		}
+		return value, err
+	})
+}
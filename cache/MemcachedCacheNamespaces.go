@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+// generationSuffix marks generation counter items. The default key normalizer
+// escapes '%' in user keys, so the suffix never collides with them.
+const generationSuffix = "%gen"
+
+// itemKey converts a user key into the key of the stored item.
+// With namespaces enabled keys that start with a namespace are versioned
+// by the current generation of that namespace.
+func (c *MemcachedCache[T]) itemKey(correlationId string, key string) (string, error) {
+	return c.versionedKey(correlationId, key, map[string]string{})
+}
+
+// itemKeys converts user keys into keys of stored items reading generation of each namespace once.
+// Returns item keys mapped to user keys and errors of user keys that can not be converted.
+func (c *MemcachedCache[T]) itemKeys(correlationId string, keys []string) (map[string]string, map[string]error) {
+	itemKeys := map[string]string{}
+	errs := map[string]error{}
+	generations := map[string]string{}
+	for _, key := range keys {
+		itemKey, err := c.versionedKey(correlationId, key, generations)
+		if err != nil {
+			errs[key] = err
+			continue
+		}
+		itemKeys[itemKey] = key
+	}
+	return itemKeys, errs
+}
+
+func (c *MemcachedCache[T]) versionedKey(correlationId string, key string, generations map[string]string) (string, error) {
+	if !c.namespaces {
+		return c.connection.NormalizeKey(correlationId, key)
+	}
+
+	ns, rest, ok := strings.Cut(key, c.namespaceSeparator)
+	if !ok || ns == "" {
+		return c.connection.NormalizeKey(correlationId, key)
+	}
+
+	generation, ok := generations[ns]
+	if !ok {
+		var err error
+		if generation, err = c.generation(correlationId, ns); err != nil {
+			return "", err
+		}
+		generations[ns] = generation
+	}
+	return c.connection.NormalizeKey(correlationId, ns+c.namespaceSeparator+generation+c.namespaceSeparator+rest)
+}
+
+func (c *MemcachedCache[T]) generationKey(correlationId string, ns string) (string, error) {
+	key, err := c.connection.NormalizeKey(correlationId, ns)
+	if err != nil {
+		return "", err
+	}
+	return c.connection.DeriveKey(correlationId, key, generationSuffix)
+}
+
+// generation reads the current generation of a namespace and initializes it when it is missing.
+func (c *MemcachedCache[T]) generation(correlationId string, ns string) (string, error) {
+	key, err := c.generationKey(correlationId, ns)
+	if err != nil {
+		return "", err
+	}
+
+	var item *memcache.Item
+	err = c.connection.Execute(correlationId, func(client *memcache.Client) (err error) {
+		item, err = client.Get(key)
+		return err
+	})
+	if err == nil {
+		return strings.TrimSpace(string(item.Value)), nil
+	}
+	if err != memcache.ErrCacheMiss {
+		return "", err
+	}
+	return c.initGeneration(correlationId, key)
+}
+
+// initGeneration starts a generation counter from the current time,
+// so entries of a namespace never become reachable again after the counter is evicted.
+func (c *MemcachedCache[T]) initGeneration(correlationId string, key string) (string, error) {
+	generation := strconv.FormatInt(time.Now().UnixNano(), 10)
+	err := c.connection.ExecuteOnce(correlationId, func(client *memcache.Client) error {
+		return client.Add(&memcache.Item{Key: key, Value: []byte(generation)})
+	})
+	if err == nil {
+		return generation, nil
+	}
+	if err != memcache.ErrNotStored {
+		return "", err
+	}
+
+	// The counter was initialized concurrently
+	var item *memcache.Item
+	err = c.connection.Execute(correlationId, func(client *memcache.Client) (err error) {
+		item, err = client.Get(key)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(item.Value)), nil
+}
+
+// ClearNamespace method are makes all values in a namespace unreachable
+// by incrementing the namespace generation. Old values are evicted by Memcached later.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - ns                a namespace to clear.
+// Retruns: an error or nil for success
+func (c *MemcachedCache[T]) ClearNamespace(ctx context.Context, correlationId string, ns string) error {
+	if state, err := c.checkOpened(correlationId); !state {
+		return err
+	}
+	if !c.namespaces {
+		return cerr.NewConfigError(correlationId, "NAMESPACES_DISABLED", "Namespaces are not enabled in options.namespaces")
+	}
+	if ns == "" || strings.Contains(ns, c.namespaceSeparator) {
+		return cerr.NewBadRequestError(correlationId, "INVALID_NAMESPACE", "Namespace can not be empty or contain the separator").
+			WithDetails("namespace", ns)
+	}
+
+	key, err := c.generationKey(correlationId, ns)
+	if err != nil {
+		return err
+	}
+
+	err = c.connection.ExecuteOnce(correlationId, func(client *memcache.Client) error {
+		_, err := client.Increment(key, 1)
+		return err
+	})
+	if err == memcache.ErrCacheMiss {
+		_, err = c.initGeneration(correlationId, key)
+	}
+	return err
+}
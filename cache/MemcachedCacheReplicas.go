@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// readFunc calls a read action on replicas of a key.
+type readFunc func(correlationId string, key string, action func(client *memcache.Client) error) error
+
+// read calls a read action on replicas of the key in order until one of them hits.
+func (c *MemcachedCache[T]) read(correlationId string, key string, action func(client *memcache.Client) error) error {
+	if c.replicas == 1 {
+		return c.connection.Execute(correlationId, action)
+	}
+	return c.connection.ExecuteRead(correlationId, key, c.replicas, action)
+}
+
+// readPrimary calls a read action on the primary replica of the key,
+// so CAS tokens are read from the replica that decides conditional writes.
+func (c *MemcachedCache[T]) readPrimary(correlationId string, key string, action func(client *memcache.Client) error) error {
+	if c.replicas == 1 {
+		return c.connection.Execute(correlationId, action)
+	}
+	return c.connection.ExecutePrimary(correlationId, key, c.replicas, action)
+}
+
+// write calls a write action on all replicas of the key and checks the quorum.
+func (c *MemcachedCache[T]) write(correlationId string, key string, action func(client *memcache.Client) error) error {
+	if c.replicas == 1 {
+		return c.connection.Execute(correlationId, action)
+	}
+	return c.connection.ExecuteWrite(correlationId, key, c.replicas, c.quorum, action)
+}
+
+// writeReplicas writes an item with a storage command to replicas of its key.
+// Commands without a replicate command are sent to all replicas. Conditional commands are decided
+// by the primary replica, and when they succeed the replicate command copies the result to other replicas.
+// Conditional commands are not idempotent, so they are not retried after they were sent.
+func (c *MemcachedCache[T]) writeReplicas(correlationId string, item *memcache.Item, write writeFunc, replicate writeFunc) error {
+	action := func(client *memcache.Client) error {
+		return write(client, item)
+	}
+	if replicate == nil {
+		return c.write(correlationId, item.Key, action)
+	}
+	if c.replicas == 1 {
+		return c.connection.ExecuteOnce(correlationId, action)
+	}
+	return c.connection.ExecutePrimaryWrite(correlationId, item.Key, c.replicas, c.quorum, action,
+		func(client *memcache.Client) error {
+			return replicate(client, item)
+		})
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+func replaceFunc(client *memcache.Client, item *memcache.Item) error {
+	return client.Replace(item)
+}
+
+func appendFunc(client *memcache.Client, item *memcache.Item) error {
+	return client.Append(item)
+}
+
+func prependFunc(client *memcache.Client, item *memcache.Item) error {
+	return client.Prepend(item)
+}
+
+// Add method are stores value in the cache only if the key is missing,
+// so the first writer wins.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - value             a value to store.
+//   - timeout           expiration timeout in milliseconds.
+// Returns: true if the value was stored, false if the key already exists, and error.
+func (c *MemcachedCache[T]) Add(ctx context.Context, correlationId string, key string, value T, timeout int64) (bool, error) {
+	return c.storeWith(correlationId, key, value, timeout, addFunc)
+}
+
+// Replace method are stores value in the cache only if the key already exists,
+// so removed values are not brought back.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - value             a value to store.
+//   - timeout           expiration timeout in milliseconds.
+// Returns: true if the value was stored, false if the key is missing, and error.
+func (c *MemcachedCache[T]) Replace(ctx context.Context, correlationId string, key string, value T, timeout int64) (bool, error) {
+	return c.storeWith(correlationId, key, value, timeout, replaceFunc)
+}
+
+// Append method are adds data to the end of an existing value.
+// It requires a codec which output can be concatenated, such as "bytes",
+// and can not be used with compression or chunking. With "options.replicas" other replicas
+// are set to the resulting value, they keep it up to "options.max_expiration".
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - value             a value to append.
+// Returns: true if the value was appended, false if the key is missing, and error.
+func (c *MemcachedCache[T]) Append(ctx context.Context, correlationId string, key string, value T) (bool, error) {
+	return c.concat(correlationId, key, value, appendFunc)
+}
+
+// Prepend method are adds data to the beginning of an existing value.
+// It requires a codec which output can be concatenated, such as "bytes",
+// and can not be used with compression or chunking. With "options.replicas" other replicas
+// are set to the resulting value, they keep it up to "options.max_expiration".
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - value             a value to prepend.
+// Returns: true if the value was prepended, false if the key is missing, and error.
+func (c *MemcachedCache[T]) Prepend(ctx context.Context, correlationId string, key string, value T) (bool, error) {
+	return c.concat(correlationId, key, value, prependFunc)
+}
+
+func (c *MemcachedCache[T]) storeWith(correlationId string, key string, value T, timeout int64, write writeFunc) (bool, error) {
+	if state, err := c.checkOpened(correlationId); !state {
+		return false, err
+	}
+	key, err := c.itemKey(correlationId, key)
+	if err != nil {
+		return false, err
+	}
+
+	expiration, err := c.connection.Expiration(correlationId, timeout)
+	if err != nil {
+		return false, err
+	}
+	item, err := c.encode(correlationId, key, value)
+	if err != nil {
+		return false, err
+	}
+	item.Expiration = expiration
+
+	err = c.writeItem(correlationId, item, write, setFunc)
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c *MemcachedCache[T]) concat(correlationId string, key string, value T, write writeFunc) (bool, error) {
+	if state, err := c.checkOpened(correlationId); !state {
+		return false, err
+	}
+	if c.codec.Id() != CodecBytes || c.compressor != nil || c.chunking {
+		return false, cerr.NewUnsupportedError(correlationId, "CONCAT_NOT_SUPPORTED",
+			"Append and prepend require bytes codec without compression and chunking").
+			WithDetails("codec", c.codec.Name())
+	}
+	key, err := c.itemKey(correlationId, key)
+	if err != nil {
+		return false, err
+	}
+
+	data, err := c.codec.Encode(value)
+	if err != nil {
+		return false, err
+	}
+	expiration, err := c.connection.Expiration(correlationId, c.connection.MaxExpiration()*1000)
+	if err != nil {
+		return false, err
+	}
+
+	// Replicas can miss the base value, so they are set to the resulting value of the primary replica.
+	// The text protocol does not return expiration, so they keep it up to the maximum expiration.
+	// When the result can not be read the replicas are removed.
+	var result *memcache.Item
+	primaryWrite := func(client *memcache.Client, item *memcache.Item) error {
+		if err := write(client, item); err != nil {
+			return err
+		}
+		if c.replicas > 1 {
+			result, _ = client.Get(item.Key)
+		}
+		return nil
+	}
+	replicate := func(client *memcache.Client, item *memcache.Item) error {
+		if result == nil {
+			err := client.Delete(item.Key)
+			if err == memcache.ErrCacheMiss {
+				return nil
+			}
+			return err
+		}
+		return client.Set(&memcache.Item{
+			Key:        result.Key,
+			Value:      result.Value,
+			Flags:      result.Flags,
+			Expiration: expiration,
+		})
+	}
+
+	// Memcached keeps flags and expiration of the existing item
+	err = c.writeItem(correlationId, &memcache.Item{Key: key, Value: data}, primaryWrite, replicate)
+	if err == memcache.ErrNotStored {
+		return false, nil
+	}
+	return err == nil, err
+}
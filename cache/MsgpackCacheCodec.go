@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgpackCacheCodec serializes cached values into MessagePack format.
+// Types are kept only by concrete value types. When T is an interface type values are decoded
+// into generic types, for instance []int becomes []interface{} and int becomes the smallest integer type
+// that holds the value, so only gob and binary codecs keep values of interface types intact.
+type MsgpackCacheCodec[T any] struct{}
+
+// NewMsgpackCacheCodec creates a new instance of the codec.
+func NewMsgpackCacheCodec[T any]() *MsgpackCacheCodec[T] {
+	return &MsgpackCacheCodec[T]{}
+}
+
+// Id returns a unique codec identifier.
+func (c *MsgpackCacheCodec[T]) Id() uint32 {
+	return CodecMsgpack
+}
+
+// Name returns a codec name.
+func (c *MsgpackCacheCodec[T]) Name() string {
+	return "msgpack"
+}
+
+// Encode serializes a value into MessagePack.
+func (c *MsgpackCacheCodec[T]) Encode(value T) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+// Decode deserializes a value from MessagePack.
+func (c *MsgpackCacheCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := msgpack.Unmarshal(data, &value)
+	return value, err
+}
@@ -0,0 +1,563 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+/*
+NearMemcachedCache are two-tier cache that keeps recently used values in a bounded in-process LRU cache
+in front of MemcachedCache, so reads of hot keys avoid a network round trip.
+
+Values are kept in the local tier for "options.local_cache.ttl" after they were read from Memcached,
+and the least recently used values are evicted when the local tier exceeds "options.local_cache.max_size".
+Writes and removes through this component invalidate the local tier, and so do values written
+by GetOrLoad and its background refreshes. ClearNamespace invalidates all local values of the namespace.
+Touches do not change values, so they only drop local values to pick up the new expiration time.
+Values known to be absent are kept in the local tier too.
+
+Without coherence checks changes made by other processes are seen after the local values expire,
+so the local timeout shall be short. With "options.local_cache.coherence" set every write through this component
+changes a version stamp item in Memcached, and a local value is evicted when the stamp read together with it
+is changed. Stamps of local values are checked with one "get" request after "options.local_cache.check_interval",
+so changes made through other instances are seen within the check interval. In "key" mode each key has its own stamp,
+in "namespace" mode keys share the stamp of their namespace, the part before "options.namespace_separator",
+so ClearNamespace is seen by other instances too. Changes made without this component are not detected.
+Stamps are not replicated with "options.replicas", stamps lost with their server are read as changed.
+
+Local values are shared by all readers, so values of reference types shall not be modified.
+
+Hits and misses of each tier are counted and returned by Stats method.
+
+Configuration parameters:
+
+All parameters of MemcachedCache and:
+
+ - options:
+   - local_cache:
+     - max_size:            maximum number of values kept in the local tier (default: 1000)
+     - ttl:                 time in milliseconds values are kept in the local tier (default: 1 sec)
+     - coherence:           version stamps to check: "none", "key" or "namespace" (default: none)
+     - check_interval:      time in milliseconds between checks of a local value, 0 to check on each read (default: 100)
+
+Example:
+	ctx := context.Background()
+
+    cache := NewNearMemcachedCache[string]();
+    cache.Configure(ctx, cconf.NewConfigParamsFromTuples(
+      "host", "localhost",
+      "port", 11211,
+      "options.local_cache.max_size", 10000,
+      "options.local_cache.ttl", 60000,
+      "options.local_cache.coherence", "key",
+    ));
+
+    err := cache.Open(ctx, "123")
+      ...
+
+    value, err := cache.Retrieve(ctx, "123", "key1")
+    stats := cache.Stats()
+*/
+type NearMemcachedCache[T any] struct {
+	*MemcachedCache[T]
+	localSize    int
+	localTimeout int64
+	coherence    string
+	checkTimeout int64
+	local        *lruCache[T]
+	localHits    int64
+	localMisses  int64
+	remoteHits   int64
+	remoteMisses int64
+}
+
+// NearCacheStats are hit and miss counters of each tier of NearMemcachedCache.
+// Values known to be absent are counted as hits.
+type NearCacheStats struct {
+	LocalHits    int64
+	LocalMisses  int64
+	RemoteHits   int64
+	RemoteMisses int64
+}
+
+// NewNearMemcachedCache method are creates a new instance of this cache.
+func NewNearMemcachedCache[T any]() *NearMemcachedCache[T] {
+	c := &NearMemcachedCache[T]{
+		MemcachedCache: NewMemcachedCache[T](),
+		localSize:      1000,
+		localTimeout:   1000,
+		coherence:      CoherenceNone,
+		checkTimeout:   100,
+	}
+	c.local = newLruCache[T](c.localSize, time.Duration(c.localTimeout)*time.Millisecond)
+	c.MemcachedCache.written = func(ctx context.Context, correlationId string, key string) {
+		c.invalidate(ctx, correlationId, key)
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// 	 - ctx context.Context
+//   - config    configuration parameters to be set.
+func (c *NearMemcachedCache[T]) Configure(ctx context.Context, config *cconf.ConfigParams) {
+	c.MemcachedCache.Configure(ctx, config)
+
+	c.localSize = config.GetAsIntegerWithDefault("options.local_cache.max_size", c.localSize)
+	c.localTimeout = config.GetAsLongWithDefault("options.local_cache.ttl", c.localTimeout)
+	c.coherence = strings.ToLower(config.GetAsStringWithDefault("options.local_cache.coherence", c.coherence))
+	c.checkTimeout = config.GetAsLongWithDefault("options.local_cache.check_interval", c.checkTimeout)
+}
+
+// Open method are opens the component.
+// Parameters:
+// 	 - ctx context.Context
+//   - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *NearMemcachedCache[T]) Open(ctx context.Context, correlationId string) error {
+	if c.localSize <= 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.local_cache.max_size shall be positive").
+			WithDetails("option", "options.local_cache.max_size").
+			WithDetails("value", c.localSize)
+	}
+	if c.localTimeout <= 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.local_cache.ttl shall be positive").
+			WithDetails("option", "options.local_cache.ttl").
+			WithDetails("value", c.localTimeout)
+	}
+	if c.coherence != CoherenceNone && c.coherence != CoherenceKey && c.coherence != CoherenceNamespace {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.local_cache.coherence has invalid value "+c.coherence).
+			WithDetails("option", "options.local_cache.coherence").
+			WithDetails("value", c.coherence)
+	}
+	if c.checkTimeout < 0 {
+		return cerr.NewConfigError(correlationId, "INVALID_OPTION", "Option options.local_cache.check_interval can not be negative").
+			WithDetails("option", "options.local_cache.check_interval").
+			WithDetails("value", c.checkTimeout)
+	}
+	c.local = newLruCache[T](c.localSize, time.Duration(c.localTimeout)*time.Millisecond)
+
+	return c.MemcachedCache.Open(ctx, correlationId)
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *NearMemcachedCache[T]) Close(ctx context.Context, correlationId string) error {
+	c.local.clear()
+	return c.MemcachedCache.Close(ctx, correlationId)
+}
+
+// Stats method are returns hit and miss counters of the local and Memcached tiers.
+func (c *NearMemcachedCache[T]) Stats() NearCacheStats {
+	return NearCacheStats{
+		LocalHits:    atomic.LoadInt64(&c.localHits),
+		LocalMisses:  atomic.LoadInt64(&c.localMisses),
+		RemoteHits:   atomic.LoadInt64(&c.remoteHits),
+		RemoteMisses: atomic.LoadInt64(&c.remoteMisses),
+	}
+}
+
+// getLocal reads a value from the local tier and counts the result.
+func (c *NearMemcachedCache[T]) getLocal(correlationId string, key string) (T, CacheState, bool) {
+	entry, ok := c.getLocalMany(correlationId, []string{key})[key]
+	return entry.value, entry.state, ok
+}
+
+// getLocalMany reads values of unique keys from the local tier and counts the results.
+// Values not checked within the check interval are returned only if their version stamps are not changed.
+func (c *NearMemcachedCache[T]) getLocalMany(correlationId string, keys []string) map[string]lruEntry[T] {
+	entries := map[string]lruEntry[T]{}
+	unchecked := map[string]lruEntry[T]{}
+	checkTimeout := time.Duration(c.checkTimeout) * time.Millisecond
+	for _, key := range keys {
+		entry, ok := c.local.get(key)
+		if !ok {
+			continue
+		}
+		if c.coherence != CoherenceNone && time.Since(entry.checked) >= checkTimeout {
+			unchecked[key] = entry
+		} else {
+			entries[key] = entry
+		}
+	}
+
+	if len(unchecked) > 0 {
+		uncheckedKeys := make([]string, 0, len(unchecked))
+		for key := range unchecked {
+			uncheckedKeys = append(uncheckedKeys, key)
+		}
+		// Values which stamps can not be read are reloaded from Memcached
+		stamps, err := c.readStamps(correlationId, uncheckedKeys)
+		for key, entry := range unchecked {
+			if err == nil && c.local.verify(key, stamps[key]) {
+				entries[key] = entry
+			}
+		}
+	}
+
+	atomic.AddInt64(&c.localHits, int64(len(entries)))
+	atomic.AddInt64(&c.localMisses, int64(len(keys)-len(entries)))
+	return entries
+}
+
+// readRemoteStamps reads version stamps of keys before their values are read from Memcached.
+// Returns false when the values shall not be kept in the local tier.
+func (c *NearMemcachedCache[T]) readRemoteStamps(ctx context.Context, correlationId string, keys []string) (map[string]string, bool) {
+	if c.coherence == CoherenceNone {
+		return map[string]string{}, true
+	}
+	stamps, err := c.readStamps(correlationId, keys)
+	if err != nil {
+		c.logger.Warn(ctx, correlationId, "Failed to read version stamps of %v: %v", keys, err)
+		return nil, false
+	}
+	return stamps, true
+}
+
+// invalidate changes version stamps of written keys and evicts them from the local tier.
+func (c *NearMemcachedCache[T]) invalidate(ctx context.Context, correlationId string, keys ...string) {
+	if c.coherence != CoherenceNone && c.IsOpen() {
+		c.bumpStamps(ctx, correlationId, keys)
+	}
+	c.local.remove(keys...)
+}
+
+// countRemote counts the result of a read from Memcached.
+func (c *NearMemcachedCache[T]) countRemote(state CacheState) {
+	if state == CacheStateMiss {
+		atomic.AddInt64(&c.remoteMisses, 1)
+	} else {
+		atomic.AddInt64(&c.remoteHits, 1)
+	}
+}
+
+// Retrieve method are retrieves cached value from the local tier or from Memcached.
+// If value is missing in the cache or expired it returns nil.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//  Retruns: cached value or error.
+func (c *NearMemcachedCache[T]) Retrieve(ctx context.Context, correlationId string, key string) (value T, err error) {
+	result, err := c.RetrieveWithState(ctx, correlationId, key)
+	return result.Value, err
+}
+
+// RetrieveWithState method are retrieves cached value from the local tier or from Memcached
+// together with its state.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//  Retruns: cached value with its state or error.
+func (c *NearMemcachedCache[T]) RetrieveWithState(ctx context.Context, correlationId string, key string) (CacheResult[T], error) {
+	if state, err := c.checkOpened(correlationId); !state {
+		return CacheResult[T]{}, err
+	}
+	if value, state, ok := c.getLocal(correlationId, key); ok {
+		return CacheResult[T]{Value: value, State: state}, nil
+	}
+
+	version := c.local.version()
+	stamps, keep := c.readRemoteStamps(ctx, correlationId, []string{key})
+	result, err := c.MemcachedCache.RetrieveWithState(ctx, correlationId, key)
+	if err != nil {
+		return result, err
+	}
+	c.countRemote(result.State)
+	if keep && result.State != CacheStateMiss {
+		c.local.put(key, result.Value, result.State, stamps[key], version)
+	}
+	return result, nil
+}
+
+// Contains check is value stores in the local tier or in Memcached.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+func (c *NearMemcachedCache[T]) Contains(ctx context.Context, correlationId string, key string) bool {
+	if state, err := c.checkOpened(correlationId); !state {
+		c.logger.Error(ctx, correlationId, err, "Connection is not opened")
+		return false
+	}
+	if _, state, ok := c.getLocal(correlationId, key); ok {
+		return state == CacheStateHit
+	}
+	return c.MemcachedCache.Contains(ctx, correlationId, key)
+}
+
+// RetrieveMany method are retrieves cached values of several keys from the local tier,
+// and the rest of them from Memcached in one round trip to each server.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - keys              unique value keys.
+// Retruns: found values by their keys, keys missing in the cache and CacheBatchError with errors of failed keys.
+func (c *NearMemcachedCache[T]) RetrieveMany(ctx context.Context, correlationId string,
+	keys []string) (values map[string]T, missing []string, err error) {
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return nil, nil, err
+	}
+
+	values = map[string]T{}
+	missing = []string{}
+	remoteKeys := []string{}
+	keys = uniqueKeys(keys)
+	entries := c.getLocalMany(correlationId, keys)
+	for _, key := range keys {
+		entry, ok := entries[key]
+		switch {
+		case !ok:
+			remoteKeys = append(remoteKeys, key)
+		case entry.state == CacheStateHit:
+			values[key] = entry.value
+		default:
+			missing = append(missing, key)
+		}
+	}
+	if len(remoteKeys) == 0 {
+		return values, missing, nil
+	}
+
+	version := c.local.version()
+	stamps, keep := c.readRemoteStamps(ctx, correlationId, remoteKeys)
+	remoteValues, remoteMissing, err := c.MemcachedCache.RetrieveMany(ctx, correlationId, remoteKeys)
+	for key, value := range remoteValues {
+		c.countRemote(CacheStateHit)
+		if keep {
+			c.local.put(key, value, CacheStateHit, stamps[key], version)
+		}
+		values[key] = value
+	}
+	for _, key := range remoteMissing {
+		c.countRemote(CacheStateMiss)
+		missing = append(missing, key)
+	}
+	return values, missing, err
+}
+
+// GetOrLoad method are reads a value from the local tier or through MemcachedCache.GetOrLoad.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - loader            a function to load the value on a miss.
+//   - timeout           expiration timeout in milliseconds of loaded value.
+// Returns: cached or loaded value, or error.
+func (c *NearMemcachedCache[T]) GetOrLoad(ctx context.Context, correlationId string, key string,
+	loader func(ctx context.Context) (T, error), timeout int64) (T, error) {
+
+	result, err := c.GetOrLoadWithState(ctx, correlationId, key, loader, timeout)
+	return result.Value, err
+}
+
+// GetOrLoadWithState method are works as GetOrLoad and also tells if the returned value is stale.
+// Stale values are not kept in the local tier, so they are refreshed as usual.
+// Loaded values are written to Memcached and invalidated in the local tier,
+// so they are kept locally when they are read again.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - loader            a function to load the value on a miss.
+//   - timeout           expiration timeout in milliseconds of loaded value.
+// Returns: cached or loaded value with its state, or error. Values known to be absent
+// are returned with CacheStateAbsent state and NotFoundError.
+func (c *NearMemcachedCache[T]) GetOrLoadWithState(ctx context.Context, correlationId string, key string,
+	loader func(ctx context.Context) (T, error), timeout int64) (CacheResult[T], error) {
+
+	if state, err := c.checkOpened(correlationId); !state {
+		return CacheResult[T]{}, err
+	}
+	if value, state, ok := c.getLocal(correlationId, key); ok {
+		if state == CacheStateAbsent {
+			return CacheResult[T]{State: CacheStateAbsent}, c.absentError(correlationId, key)
+		}
+		return CacheResult[T]{Value: value, State: state}, nil
+	}
+
+	version := c.local.version()
+	stamps, keep := c.readRemoteStamps(ctx, correlationId, []string{key})
+	result, err := c.MemcachedCache.GetOrLoadWithState(ctx, correlationId, key, loader, timeout)
+	c.countRemote(result.State)
+	switch {
+	case !keep:
+	case result.State == CacheStateAbsent:
+		if c.negativeTtl > 0 {
+			c.local.put(key, result.Value, CacheStateAbsent, stamps[key], version)
+		}
+	case err == nil && result.State == CacheStateHit && !result.Stale:
+		c.local.put(key, result.Value, CacheStateHit, stamps[key], version)
+	}
+	return result, err
+}
+
+// Store method are stores value in Memcached and invalidates it in the local tier.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+//   - value             a value to store.
+//   - timeout           expiration timeout in milliseconds.
+// Returns: error or nil for success
+func (c *NearMemcachedCache[T]) Store(ctx context.Context, correlationId string, key string, value T, timeout int64) (result T, err error) {
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Store(ctx, correlationId, key, value, timeout)
+}
+
+// StoreAbsent method are caches a marker that the value is known to be absent
+// and invalidates the value in the local tier.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+// Returns: error or nil for success
+func (c *NearMemcachedCache[T]) StoreAbsent(ctx context.Context, correlationId string, key string) error {
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.StoreAbsent(ctx, correlationId, key)
+}
+
+// Remove method are removes a value from Memcached and from the local tier.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a unique value key.
+// Retruns: an error or nil for success
+func (c *NearMemcachedCache[T]) Remove(ctx context.Context, correlationId string, key string) error {
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Remove(ctx, correlationId, key)
+}
+
+// StoreMany method are stores several values in Memcached and invalidates them in the local tier.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - items             items to store.
+// Returns: CacheBatchError with errors of failed keys or nil for success
+func (c *NearMemcachedCache[T]) StoreMany(ctx context.Context, correlationId string, items []CacheItem[T]) error {
+	keys := make([]string, len(items))
+	for index, item := range items {
+		keys[index] = item.Key
+	}
+	defer c.invalidate(ctx, correlationId, keys...)
+	return c.MemcachedCache.StoreMany(ctx, correlationId, items)
+}
+
+// RemoveMany method are removes several values from Memcached and from the local tier.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - keys              unique value keys.
+// Retruns: CacheBatchError with errors of failed keys or nil for success
+func (c *NearMemcachedCache[T]) RemoveMany(ctx context.Context, correlationId string, keys []string) error {
+	defer c.invalidate(ctx, correlationId, keys...)
+	return c.MemcachedCache.RemoveMany(ctx, correlationId, keys)
+}
+
+// Add method are stores value in Memcached only if the key is missing
+// and invalidates it in the local tier.
+// See MemcachedCache.Add
+func (c *NearMemcachedCache[T]) Add(ctx context.Context, correlationId string, key string, value T, timeout int64) (bool, error) {
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Add(ctx, correlationId, key, value, timeout)
+}
+
+// Replace method are stores value in Memcached only if the key already exists
+// and invalidates it in the local tier.
+// See MemcachedCache.Replace
+func (c *NearMemcachedCache[T]) Replace(ctx context.Context, correlationId string, key string, value T, timeout int64) (bool, error) {
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Replace(ctx, correlationId, key, value, timeout)
+}
+
+// Append method are adds data to the end of a value in Memcached and invalidates it in the local tier.
+// See MemcachedCache.Append
+func (c *NearMemcachedCache[T]) Append(ctx context.Context, correlationId string, key string, value T) (bool, error) {
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Append(ctx, correlationId, key, value)
+}
+
+// Prepend method are adds data to the beginning of a value in Memcached and invalidates it in the local tier.
+// See MemcachedCache.Prepend
+func (c *NearMemcachedCache[T]) Prepend(ctx context.Context, correlationId string, key string, value T) (bool, error) {
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Prepend(ctx, correlationId, key, value)
+}
+
+// CompareAndSwap method are stores value in Memcached if it was not changed since it was read
+// and invalidates it in the local tier.
+// See MemcachedCache.CompareAndSwap
+func (c *NearMemcachedCache[T]) CompareAndSwap(ctx context.Context, correlationId string, key string, value T,
+	cas uint64, timeout int64) (bool, error) {
+
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.CompareAndSwap(ctx, correlationId, key, value, cas, timeout)
+}
+
+// Update method are changes a value in Memcached with read-modify-write cycles
+// and invalidates it in the local tier.
+// See MemcachedCache.Update
+func (c *NearMemcachedCache[T]) Update(ctx context.Context, correlationId string, key string,
+	update func(old T, found bool) (T, error), timeout int64) (result T, err error) {
+
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Update(ctx, correlationId, key, update, timeout)
+}
+
+// Increment method are atomically increments a counter in Memcached and invalidates it in the local tier.
+// See MemcachedCache.Increment
+func (c *NearMemcachedCache[T]) Increment(ctx context.Context, correlationId string, key string,
+	delta uint64, initial int64, timeout int64) (uint64, error) {
+
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Increment(ctx, correlationId, key, delta, initial, timeout)
+}
+
+// Decrement method are atomically decrements a counter in Memcached and invalidates it in the local tier.
+// See MemcachedCache.Decrement
+func (c *NearMemcachedCache[T]) Decrement(ctx context.Context, correlationId string, key string,
+	delta uint64, initial int64, timeout int64) (uint64, error) {
+
+	defer c.invalidate(ctx, correlationId, key)
+	return c.MemcachedCache.Decrement(ctx, correlationId, key, delta, initial, timeout)
+}
+
+// Touch method are updates expiration time of a value in Memcached and drops it from the local tier.
+// Version stamps are not changed, so local values of other instances are kept.
+// See MemcachedCache.Touch
+func (c *NearMemcachedCache[T]) Touch(ctx context.Context, correlationId string, key string, timeout int64) (bool, error) {
+	defer c.local.remove(key)
+	return c.MemcachedCache.Touch(ctx, correlationId, key, timeout)
+}
+
+// RetrieveAndTouch method are retrieves a value from Memcached, updates its expiration time
+// and drops it from the local tier. Version stamps are not changed.
+// See MemcachedCache.RetrieveAndTouch
+func (c *NearMemcachedCache[T]) RetrieveAndTouch(ctx context.Context, correlationId string, key string,
+	timeout int64) (T, error) {
+
+	defer c.local.remove(key)
+	return c.MemcachedCache.RetrieveAndTouch(ctx, correlationId, key, timeout)
+}
+
+// ClearNamespace method are makes all values in a namespace unreachable
+// and invalidates them in the local tier.
+// See MemcachedCache.ClearNamespace
+func (c *NearMemcachedCache[T]) ClearNamespace(ctx context.Context, correlationId string, ns string) error {
+	defer func() {
+		if c.coherence == CoherenceNamespace && c.IsOpen() {
+			c.bumpStamps(ctx, correlationId, []string{ns + c.namespaceSeparator})
+		}
+		c.local.removePrefix(ns + c.namespaceSeparator)
+	}()
+	return c.MemcachedCache.ClearNamespace(ctx, correlationId, ns)
+}
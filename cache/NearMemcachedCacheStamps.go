@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// Coherence modes of the local tier of NearMemcachedCache.
+const (
+	// CoherenceNone keeps local values until they expire
+	CoherenceNone = "none"
+	// CoherenceKey checks a version stamp of each key
+	CoherenceKey = "key"
+	// CoherenceNamespace checks a version stamp of the key namespace
+	CoherenceNamespace = "namespace"
+)
+
+// stampSuffix and namespaceStampSuffix mark version stamps of keys and namespaces.
+// The default key normalizer escapes '%' in user keys, so the suffixes never collide with them.
+const (
+	stampSuffix          = "%ver"
+	namespaceStampSuffix = "%nsver"
+)
+
+// stampKey returns the key of the version stamp that covers a user key.
+// In namespace mode keys without a namespace have their own stamps.
+func (c *NearMemcachedCache[T]) stampKey(correlationId string, key string) (string, error) {
+	if c.coherence == CoherenceNamespace {
+		if ns, _, ok := strings.Cut(key, c.namespaceSeparator); ok && ns != "" {
+			nsKey, err := c.connection.NormalizeKey(correlationId, ns)
+			if err != nil {
+				return "", err
+			}
+			return c.connection.DeriveKey(correlationId, nsKey, namespaceStampSuffix)
+		}
+	}
+
+	itemKey, err := c.connection.NormalizeKey(correlationId, key)
+	if err != nil {
+		return "", err
+	}
+	return c.connection.DeriveKey(correlationId, itemKey, stampSuffix)
+}
+
+// stampKeys returns stamp keys mapped to user keys they cover.
+func (c *NearMemcachedCache[T]) stampKeys(correlationId string, keys []string) (map[string][]string, error) {
+	stampKeys := map[string][]string{}
+	for _, key := range keys {
+		stampKey, err := c.stampKey(correlationId, key)
+		if err != nil {
+			return nil, err
+		}
+		stampKeys[stampKey] = append(stampKeys[stampKey], key)
+	}
+	return stampKeys, nil
+}
+
+// readStamps reads version stamps of user keys in one request to each server.
+// Missing stamps are returned as empty strings.
+func (c *NearMemcachedCache[T]) readStamps(correlationId string, keys []string) (map[string]string, error) {
+	stampKeys, err := c.stampKeys(correlationId, keys)
+	if err != nil {
+		return nil, err
+	}
+	itemKeys := make([]string, 0, len(stampKeys))
+	for stampKey := range stampKeys {
+		itemKeys = append(itemKeys, stampKey)
+	}
+
+	var items map[string]*memcache.Item
+	err = c.connection.Execute(correlationId, func(client *memcache.Client) (err error) {
+		items, err = client.GetMulti(itemKeys)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stamps := map[string]string{}
+	for stampKey, userKeys := range stampKeys {
+		stamp := ""
+		if item, ok := items[stampKey]; ok {
+			stamp = strings.TrimSpace(string(item.Value))
+		}
+		for _, key := range userKeys {
+			stamps[key] = stamp
+		}
+	}
+	return stamps, nil
+}
+
+// bumpStamps changes version stamps of user keys, so other processes evict their local values.
+// New stamps start from the current time, so evicted stamps never return to values seen before.
+func (c *NearMemcachedCache[T]) bumpStamps(ctx context.Context, correlationId string, keys []string) {
+	stampKeys, err := c.stampKeys(correlationId, keys)
+	if err != nil {
+		c.logger.Warn(ctx, correlationId, "Failed to change version stamps of %v: %v", keys, err)
+		return
+	}
+
+	for stampKey := range stampKeys {
+		err := c.connection.ExecuteOnce(correlationId, func(client *memcache.Client) error {
+			_, err := client.Increment(stampKey, 1)
+			if err != memcache.ErrCacheMiss {
+				return err
+			}
+			stamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+			err = client.Add(&memcache.Item{Key: stampKey, Value: []byte(stamp)})
+			if err != memcache.ErrNotStored {
+				return err
+			}
+			// The stamp was created concurrently
+			_, err = client.Increment(stampKey, 1)
+			return err
+		})
+		if err != nil {
+			c.logger.Warn(ctx, correlationId, "Failed to change version stamp %s: %v", stampKey, err)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package cache
+
+import (
+	"github.com/golang/snappy"
+)
+
+// SnappyCacheCompressor compresses cached values using snappy block format.
+type SnappyCacheCompressor struct{}
+
+// NewSnappyCacheCompressor creates a new instance of the compressor.
+func NewSnappyCacheCompressor() *SnappyCacheCompressor {
+	return &SnappyCacheCompressor{}
+}
+
+// Id returns a unique compressor identifier.
+func (c *SnappyCacheCompressor) Id() uint32 {
+	return CompressionSnappy
+}
+
+// Name returns a compressor name.
+func (c *SnappyCacheCompressor) Name() string {
+	return "snappy"
+}
+
+// Compress compresses serialized value.
+func (c *SnappyCacheCompressor) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+// Decompress restores serialized value.
+func (c *SnappyCacheCompressor) Decompress(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
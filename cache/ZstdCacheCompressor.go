@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ZstdCacheCompressor compresses cached values using zstd.
+// Encoder and decoder are created on first use and shared between calls.
+type ZstdCacheCompressor struct {
+	once    sync.Once
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+	err     error
+}
+
+// NewZstdCacheCompressor creates a new instance of the compressor.
+func NewZstdCacheCompressor() *ZstdCacheCompressor {
+	return &ZstdCacheCompressor{}
+}
+
+// Id returns a unique compressor identifier.
+func (c *ZstdCacheCompressor) Id() uint32 {
+	return CompressionZstd
+}
+
+// Name returns a compressor name.
+func (c *ZstdCacheCompressor) Name() string {
+	return "zstd"
+}
+
+func (c *ZstdCacheCompressor) init() error {
+	c.once.Do(func() {
+		c.encoder, c.err = zstd.NewWriter(nil)
+		if c.err == nil {
+			c.decoder, c.err = zstd.NewReader(nil)
+		}
+	})
+	return c.err
+}
+
+// Compress compresses serialized value.
+func (c *ZstdCacheCompressor) Compress(data []byte) ([]byte, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+// Decompress restores serialized value.
+func (c *ZstdCacheCompressor) Decompress(data []byte) ([]byte, error) {
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return c.decoder.DecodeAll(data, nil)
+}
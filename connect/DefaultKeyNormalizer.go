@@ -0,0 +1,50 @@
+package connect
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// DefaultKeyNormalizer escapes illegal characters in keys and hashes overlong keys.
+// Spaces, control characters and '%' are escaped as %XX, so different keys never collide.
+// Keys that are still longer than the maximum key size are truncated and
+// suffixed with SHA-1 of the original key.
+type DefaultKeyNormalizer struct{}
+
+// NewDefaultKeyNormalizer creates a new instance of the normalizer.
+func NewDefaultKeyNormalizer() *DefaultKeyNormalizer {
+	return &DefaultKeyNormalizer{}
+}
+
+// Normalize converts a user key into Memcached key.
+func (c *DefaultKeyNormalizer) Normalize(key string, maxKeySize int) string {
+	var builder strings.Builder
+	builder.Grow(len(key))
+	for i := 0; i < len(key); i++ {
+		b := key[i]
+		if b == '%' || isIllegalKeyByte(b) {
+			fmt.Fprintf(&builder, "%%%02X", b)
+		} else {
+			builder.WriteByte(b)
+		}
+	}
+	result := builder.String()
+
+	if len(result) <= maxKeySize {
+		return result
+	}
+
+	hash := sha1.Sum([]byte(key))
+	suffix := ":" + hex.EncodeToString(hash[:])
+	prefixSize := maxKeySize - len(suffix)
+	if prefixSize <= 0 {
+		return suffix[1:]
+	}
+	return result[:prefixSize] + suffix
+}
+
+func isIllegalKeyByte(b byte) bool {
+	return b <= ' ' || b == 0x7f
+}
@@ -0,0 +1,187 @@
+package connect
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+var errServerDead = errors.New("memcache: server is marked as dead")
+
+// maxRehashes is a number of attempts to pick a live server for a key of a dead server.
+const maxRehashes = 20
+
+type serverHealth struct {
+	failures  int
+	deadSince time.Time
+}
+
+// healthSelector distributes keys between Memcached servers with a server selector and keeps
+// dead servers out of service until the retry timeout expires.
+type healthSelector struct {
+	mtx       sync.Mutex
+	servers   []*MemcachedServer
+	addresses []string
+	selector  IServerSelector
+	health    map[string]*serverHealth
+	failures  int
+	retry     time.Duration
+	remove    bool
+}
+
+func newHealthSelector(servers []*MemcachedServer, selector IServerSelector,
+	failures int, retry time.Duration, remove bool) (*healthSelector, error) {
+
+	if err := selector.SetServers(servers); err != nil {
+		return nil, err
+	}
+
+	addresses := make([]string, len(servers))
+	for index, server := range servers {
+		addresses[index] = server.Addr.String()
+	}
+
+	return &healthSelector{
+		servers:   servers,
+		addresses: addresses,
+		selector:  selector,
+		health:    map[string]*serverHealth{},
+		failures:  failures,
+		retry:     retry,
+		remove:    remove,
+	}, nil
+}
+
+// PickServer returns the server address that a given item should be shared onto.
+// With dead servers removed their keys are rehashed to live servers,
+// while keys of live servers stay in place.
+func (s *healthSelector) PickServer(key string) (net.Addr, error) {
+	server, err := s.selector.PickServer(key)
+	if err != nil {
+		return nil, err
+	}
+	if s.isAvailable(server.Addr.String()) {
+		return server.Addr, nil
+	}
+	if !s.remove {
+		return nil, errServerDead
+	}
+
+	for attempt := 1; attempt <= maxRehashes; attempt++ {
+		server, err = s.selector.PickServer(strconv.Itoa(attempt) + "-" + key)
+		if err == nil && s.isAvailable(server.Addr.String()) {
+			return server.Addr, nil
+		}
+	}
+	for index, address := range s.addresses {
+		if s.isAvailable(address) {
+			return s.servers[index].Addr, nil
+		}
+	}
+	return nil, memcache.ErrNoServers
+}
+
+// PickReplicas returns up to count distinct servers that keep replicas of a key in order of preference.
+// With dead servers removed replicas are picked among live servers, otherwise
+// dead servers stay in the list and operations on their replicas fail.
+func (s *healthSelector) PickReplicas(key string, count int) ([]*MemcachedServer, error) {
+	if count > len(s.servers) {
+		count = len(s.servers)
+	}
+	if !s.remove {
+		return s.pickOrdered(key, count)
+	}
+
+	ordered, err := s.pickOrdered(key, len(s.servers))
+	if err != nil {
+		return nil, err
+	}
+	servers := []*MemcachedServer{}
+	for _, server := range ordered {
+		if len(servers) < count && s.isAvailable(server.Addr.String()) {
+			servers = append(servers, server)
+		}
+	}
+	if len(servers) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	return servers, nil
+}
+
+// pickOrdered returns count distinct servers for a key in order of preference.
+// Selectors that do not implement IReplicaSelector are asked for rehashed keys,
+// and the list is completed with servers in their configured order.
+func (s *healthSelector) pickOrdered(key string, count int) ([]*MemcachedServer, error) {
+	if selector, ok := s.selector.(IReplicaSelector); ok {
+		return selector.PickServers(key, count)
+	}
+
+	server, err := s.selector.PickServer(key)
+	if err != nil {
+		return nil, err
+	}
+	servers := []*MemcachedServer{server}
+	picked := map[string]bool{server.Addr.String(): true}
+	add := func(server *MemcachedServer) {
+		if len(servers) < count && !picked[server.Addr.String()] {
+			picked[server.Addr.String()] = true
+			servers = append(servers, server)
+		}
+	}
+	for attempt := 1; attempt <= maxRehashes && len(servers) < count; attempt++ {
+		if server, err := s.selector.PickServer(strconv.Itoa(attempt) + "-" + key); err == nil {
+			add(server)
+		}
+	}
+	for _, server := range s.servers {
+		add(server)
+	}
+	return servers, nil
+}
+
+// Each iterates over each server calling the given function.
+func (s *healthSelector) Each(f func(net.Addr) error) error {
+	for _, server := range s.servers {
+		if err := f(server.Addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *healthSelector) isAvailable(address string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	health, ok := s.health[address]
+	if !ok || health.failures < s.failures {
+		return true
+	}
+	return time.Since(health.deadSince) >= s.retry
+}
+
+func (s *healthSelector) markFailed(address string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	health, ok := s.health[address]
+	if !ok {
+		health = &serverHealth{}
+		s.health[address] = health
+	}
+	health.failures++
+	if health.failures >= s.failures {
+		health.deadSince = time.Now()
+	}
+}
+
+func (s *healthSelector) markAlive(address string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	delete(s.health, address)
+}
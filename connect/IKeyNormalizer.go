@@ -0,0 +1,11 @@
+package connect
+
+// IKeyNormalizer interface for policies that convert user keys
+// into keys acceptable by Memcached: at most maximum key size long
+// and without spaces or control characters.
+// Normalization shall be deterministic, so the same key is always mapped to the same Memcached key.
+type IKeyNormalizer interface {
+
+	// Normalize converts a user key into Memcached key.
+	Normalize(key string, maxKeySize int) string
+}
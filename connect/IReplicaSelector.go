@@ -0,0 +1,11 @@
+package connect
+
+// IReplicaSelector interface for server selectors that pick several distinct servers
+// to keep replicas of a key. Replicas of keys distributed by selectors that do not
+// implement it are picked by rehashing the key.
+type IReplicaSelector interface {
+
+	// PickServers returns up to count distinct servers for a given key in order of preference.
+	// The first server shall be the one returned by PickServer.
+	PickServers(key string, count int) ([]*MemcachedServer, error)
+}
@@ -0,0 +1,14 @@
+package connect
+
+// IServerSelector interface for algorithms that distribute keys between Memcached servers.
+// Distribution shall be deterministic, so the same key is always mapped to the same server
+// while the server list is not changed. Servers are set once when the connection is opened,
+// so a selector shall not be shared by connections to different servers.
+type IServerSelector interface {
+
+	// SetServers sets servers to distribute keys between.
+	SetServers(servers []*MemcachedServer) error
+
+	// PickServer returns the server that a given key is stored on.
+	PickServer(key string) (*MemcachedServer, error)
+}
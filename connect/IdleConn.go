@@ -0,0 +1,85 @@
+package connect
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// idleConn is a pooled connection that is transparently reestablished
+// when it was idle longer than the configured idle timeout. It also reports
+// network failures and responses of the server to the server selector.
+type idleConn struct {
+	net.Conn
+	connection *MemcachedConnection
+	network    string
+	address    string
+	lastUsed   time.Time
+	deadline   time.Time
+}
+
+func newIdleConn(conn net.Conn, connection *MemcachedConnection, network string, address string) *idleConn {
+	return &idleConn{
+		Conn:       conn,
+		connection: connection,
+		network:    network,
+		address:    address,
+		lastUsed:   time.Now(),
+	}
+}
+
+func (c *idleConn) SetDeadline(t time.Time) error {
+	c.deadline = t
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *idleConn) Write(b []byte) (int, error) {
+	idle := time.Duration(c.connection.idle) * time.Millisecond
+	if idle > 0 && time.Since(c.lastUsed) > idle {
+		if err := c.reestablish(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := c.Conn.Write(b)
+	c.used(err, false)
+	return n, err
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.used(err, true)
+	return n, err
+}
+
+func (c *idleConn) used(err error, read bool) {
+	c.lastUsed = time.Now()
+	var netErr net.Error
+	switch {
+	case err == nil:
+		// A response from the server shows it is alive
+		if read {
+			c.connection.markAlive(c.address)
+		}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		// Slow commands time out on live servers, they are not connection failures
+	default:
+		c.connection.markFailed(c.address)
+	}
+}
+
+func (c *idleConn) reestablish() error {
+	c.Conn.Close()
+
+	conn, err := c.connection.dialServer(c.network, c.address)
+	if err != nil {
+		return err
+	}
+	if !c.deadline.IsZero() {
+		conn.SetDeadline(c.deadline)
+	}
+
+	c.Conn = conn
+	c.lastUsed = time.Now()
+	return nil
+}
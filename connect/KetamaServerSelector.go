@@ -0,0 +1,117 @@
+package connect
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// ketamaPointsPerServer is an average number of continuum points of a server.
+const ketamaPointsPerServer = 160
+
+type ketamaPoint struct {
+	hash   uint32
+	server *MemcachedServer
+}
+
+// KetamaServerSelector maps keys to servers with a consistent hash continuum,
+// so changes of the server list remap only keys of added or removed servers.
+// The continuum and key hashes are compatible with libmemcached weighted ketama
+// (MEMCACHED_BEHAVIOR_KETAMA_WEIGHTED), so keys are placed the same way as by
+// libmemcached, pylibmc and other ketama clients with the same server names and weights.
+type KetamaServerSelector struct {
+	mtx    sync.RWMutex
+	points []ketamaPoint
+}
+
+// NewKetamaServerSelector creates a new instance of the selector.
+func NewKetamaServerSelector() *KetamaServerSelector {
+	return &KetamaServerSelector{}
+}
+
+// SetServers builds the continuum of the servers.
+func (c *KetamaServerSelector) SetServers(servers []*MemcachedServer) error {
+	totalWeight := 0
+	for _, server := range servers {
+		totalWeight += server.Weight
+	}
+
+	points := []ketamaPoint{}
+	for _, server := range servers {
+		// Float arithmetic follows libmemcached to get the same number of points
+		pct := float32(server.Weight) / float32(totalWeight)
+		hashes := int(math.Floor(float64(float32(float64(pct*ketamaPointsPerServer/4*float32(len(servers))) + 0.0000000001))))
+
+		name := server.Name()
+		for hashIndex := 0; hashIndex < hashes; hashIndex++ {
+			digest := md5.Sum([]byte(name + "-" + strconv.Itoa(hashIndex)))
+			for alignment := 0; alignment < 4; alignment++ {
+				points = append(points, ketamaPoint{
+					hash:   binary.LittleEndian.Uint32(digest[alignment*4:]),
+					server: server,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].hash < points[j].hash
+	})
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.points = points
+	return nil
+}
+
+// PickServer returns the server of the first continuum point at or after the key hash.
+func (c *KetamaServerSelector) PickServer(key string) (*MemcachedServer, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if len(c.points) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+
+	digest := md5.Sum([]byte(key))
+	hash := binary.LittleEndian.Uint32(digest[:4])
+	index := sort.Search(len(c.points), func(i int) bool {
+		return c.points[i].hash >= hash
+	})
+	if index == len(c.points) {
+		index = 0
+	}
+	return c.points[index].server, nil
+}
+
+// PickServers walks the continuum from the key hash and returns the first distinct servers it meets.
+func (c *KetamaServerSelector) PickServers(key string, count int) ([]*MemcachedServer, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if len(c.points) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+
+	digest := md5.Sum([]byte(key))
+	hash := binary.LittleEndian.Uint32(digest[:4])
+	start := sort.Search(len(c.points), func(i int) bool {
+		return c.points[i].hash >= hash
+	})
+
+	servers := []*MemcachedServer{}
+	picked := map[*MemcachedServer]bool{}
+	for offset := 0; offset < len(c.points) && len(servers) < count; offset++ {
+		server := c.points[(start+offset)%len(c.points)].server
+		if !picked[server] {
+			picked[server] = true
+			servers = append(servers, server)
+		}
+	}
+	return servers, nil
+}
@@ -0,0 +1,54 @@
+package connect
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+const asciiAuthKey = "auth"
+
+// authenticate performs authentication handshake on a freshly established connection
+// with text protocol authentication of memcached 1.5.15+ started with -Y option.
+// SASL authentication is not supported: memcached accepts it only in the binary protocol,
+// while gomemcache sends text protocol commands.
+func authenticate(conn net.Conn, username string, password string) error {
+	return authenticateAscii(conn, username, password)
+}
+
+func authenticateAscii(conn net.Conn, username string, password string) error {
+	data := username + " " + password
+	request := "set " + asciiAuthKey + " 0 0 " + strconv.Itoa(len(data)) + "\r\n" + data + "\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		return err
+	}
+
+	line, err := readLine(conn)
+	if err != nil {
+		return err
+	}
+	if line != "STORED" {
+		return cerr.NewUnauthorizedError("", "AUTH_FAILED", "Authentication failed for user "+username).
+			WithDetails("message", line)
+	}
+	return nil
+}
+
+// readLine reads a response line byte by byte to leave nothing buffered
+// that belongs to the following responses.
+func readLine(conn net.Conn) (string, error) {
+	var line strings.Builder
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return "", err
+		}
+		if b[0] == '\n' {
+			return strings.TrimSuffix(line.String(), "\r"), nil
+		}
+		line.WriteByte(b[0])
+	}
+}
@@ -0,0 +1,795 @@
+package connect
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	cref "github.com/pip-services3-gox/pip-services3-commons-gox/refer"
+	cauth "github.com/pip-services3-gox/pip-services3-components-gox/auth"
+	ccon "github.com/pip-services3-gox/pip-services3-components-gox/connect"
+)
+
+// NoExpiration is a timeout to keep items until they are removed.
+const NoExpiration int64 = 0
+
+// Key distribution algorithms selected by "options.distribution".
+const (
+	distributionModulo     = "modulo"
+	distributionKetama     = "ketama"
+	distributionRendezvous = "rendezvous"
+)
+
+// maxRelativeExpiration is the longest expiration in seconds that Memcached treats as relative.
+const maxRelativeExpiration = 60 * 60 * 24 * 30
+
+/*
+MemcachedConnection is a connection to Memcached servers that is shared by
+MemcachedCache and MemcachedLock components. It resolves server addresses and credentials,
+applies client options, retries failed operations and keeps track of dead servers.
+
+Connections with "tls" protocol are encrypted using TLS (memcached 1.6 started with --enable-ssl option).
+When credentials are configured every new connection is authenticated before use.
+
+Connections are authenticated with text protocol authentication of memcached 1.5.15+ started with -Y option,
+which separates the user name from the password by a space, so user names with spaces are rejected.
+SASL authentication of memcached started with -S option is not supported, as it requires the binary protocol
+and gomemcache speaks only the text protocol.
+
+Timeouts in milliseconds are rounded up to whole seconds and reduced to max_expiration.
+Timeouts longer than 30 days are sent as absolute Unix time. Timeout 0 keeps items until they are removed,
+it is rejected with BadRequestError unless allow_no_expiration is enabled.
+
+User keys are prefixed with key_prefix and converted by a key normalizer before they are sent to Memcached.
+The prefix keeps keys of components sharing the same servers apart.
+The default normalizer escapes spaces and control characters, and replaces the tail
+of keys longer than max_key_size with SHA-1 of the key. Keys that are still invalid are rejected with BadRequestError.
+
+Keys are distributed between servers by a server selector chosen by "distribution" option.
+The "modulo" selector and custom selectors get servers in the configured order,
+the other selectors get them sorted by host and port.
+The "modulo" selector takes CRC32 key hash modulo the number of servers, as gomemcache does by default,
+so adding or removing a server remaps most of the keys. The "ketama" selector places servers
+on a consistent hash continuum in proportion to their weights, and a change of the server list remaps
+only keys of the changed servers. It matches libmemcached weighted ketama distribution, so clients
+of other languages sharing the cluster find keys on the same servers when they use the same host names,
+ports and weights. The "rendezvous" selector uses highest random weight hashing
+that distributes keys more evenly and also remaps only keys of the changed servers.
+Custom IServerSelector implementations are set by SetServerSelector method or found in references,
+they replace the "distribution" option.
+With "remove" option enabled keys of dead servers are rehashed to live servers.
+
+ExecuteRead, ExecuteWrite and ExecutePrimaryWrite methods process replicas of a key kept on several distinct servers.
+The built-in selectors pick the next servers of the modulo list, the ketama continuum or by rendezvous scores,
+custom selectors implement IReplicaSelector or get replicas by rehashing the key.
+
+Configuration parameters:
+
+ - connection(s):
+   - discovery_key:         (optional) a key to retrieve the connection from IDiscovery
+   - host:                  host name or IP address
+   - port:                  port number
+   - uri:                   resource URI or connection string with all parameters in it
+   - protocol:              (optional) "tls" to encrypt the connection
+   - weight:                (optional) relative share of keys stored on the server with ketama and rendezvous distribution (default: 1)
+ - credential(s):
+   - store_key:             (optional) a key to retrieve the credentials from ICredentialStore
+   - username:              user name
+   - password:              user password
+ - options:
+   - ssl_ca_file:           (optional) CA bundle file to verify server certificates
+   - ssl_cert_file:         (optional) client certificate file
+   - ssl_key_file:          (optional) client private key file
+   - ssl_server_name:       (optional) server name to verify, by default the connection host is used
+   - ssl_insecure_skip_verify: skip verification of server certificates (default: false)
+   - key_prefix:            (optional) prefix added to all keys, for instance "myservice:"
+   - key_normalizer:        key policy: "default" to escape illegal characters and hash long keys, "none" to keep keys unchanged (default: default)
+   - max_key_size:          maximum key length (default: 250)
+   - max_expiration:        maximum expiration duration in seconds up to 2147483647, longer timeouts are reduced to it (default: 2592000)
+   - allow_no_expiration:   accept timeout 0 to keep items until they are removed (default: false)
+   - max_value:             maximum value length (default: 1048576)
+   - pool_size:             maximum number of idle connections kept per server (default: 5)
+   - reconnect:             timeout to establish a connection in milliseconds (default: 10 sec)
+   - timeout:               socket read/write timeout in milliseconds (default: 5 sec)
+   - retries:               number of retries of an operation failed on a network error, commands that are not idempotent are retried only when the connection failed (default: 3)
+   - failures:              number of consecutive connection failures before a server is marked as dead, command timeouts are not counted (default: 5)
+   - retry:                 time a dead server stays out of service in milliseconds (default: 30 sec)
+   - remove:                redistribute keys of dead servers to live ones (default: false)
+   - distribution:          key distribution between servers: "modulo", "ketama" or "rendezvous" (default: modulo)
+   - idle:                  idle timeout before a pooled connection is reestablished in milliseconds, 0 to disable (default: 5 sec)
+
+References:
+
+- *:discovery:*:*:1.0         (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0  (optional) Credential stores to resolve credentials
+- *:server-selector:*:*:1.0   (optional) IServerSelector to distribute keys between servers
+
+Example:
+	ctx := context.Background()
+
+	connection := NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", "localhost",
+		"connection.port", 11211,
+		"options.pool_size", 10,
+	))
+
+	err := connection.Open(ctx, "123")
+	...
+
+	err = connection.Execute("123", func(client *memcache.Client) error {
+		return client.Set(&memcache.Item{Key: "key1", Value: []byte("ABC")})
+	})
+*/
+type MemcachedConnection struct {
+	connectionResolver *ccon.ConnectionResolver
+	credentialResolver *cauth.CredentialResolver
+	username           string
+	password           string
+	sslCaFile          string
+	sslCertFile        string
+	sslKeyFile         string
+	sslServerName      string
+	sslInsecure        bool
+	tlsConfig          *tls.Config
+	tlsServers         map[string]string
+	keyPrefix          string
+	keyNormalizerName  string
+	keyNormalizer      IKeyNormalizer
+	maxKeySize         int
+	maxExpiration      int64
+	allowNoExpiration  bool
+	maxValue           int
+	poolSize           int
+	reconnect          int
+	timeout            int
+	retries            int
+	failures           int
+	retry              int
+	remove             bool
+	distribution       string
+	serverSelector     IServerSelector
+	serverOrder        map[string]int
+	idle               int
+	configErr          *cerr.ApplicationError
+	selector           *healthSelector
+	client             *memcache.Client
+	serverClients      map[string]*memcache.Client
+}
+
+// NewMemcachedConnection method are creates a new instance of the connection.
+func NewMemcachedConnection() *MemcachedConnection {
+	c := &MemcachedConnection{
+		connectionResolver: ccon.NewEmptyConnectionResolver(),
+		credentialResolver: cauth.NewEmptyCredentialResolver(),
+		keyNormalizerName:  "default",
+		keyNormalizer:      NewDefaultKeyNormalizer(),
+		maxKeySize:         250,
+		maxExpiration:      2592000,
+		maxValue:           1048576,
+		poolSize:           5,
+		reconnect:          10000,
+		timeout:            5000,
+		retries:            3,
+		failures:           5,
+		retry:              30000,
+		remove:             false,
+		distribution:       distributionModulo,
+		serverOrder:        map[string]int{},
+		idle:               5000,
+		client:             nil,
+	}
+	return c
+}
+
+// Configure method are configures component by passing configuration parameters.
+// Invalid option values are reported by the Open method as ConfigError.
+//   - ctx context.Context
+//   - config    configuration parameters to be set.
+func (c *MemcachedConnection) Configure(ctx context.Context, config *cconf.ConfigParams) {
+	c.connectionResolver.Configure(ctx, config)
+	c.credentialResolver.Configure(ctx, config)
+	c.configureServerOrder(config)
+
+	c.configErr = nil
+	c.keyPrefix = config.GetAsStringWithDefault("options.key_prefix", c.keyPrefix)
+	c.keyNormalizerName = c.getAsEnum(config, "options.key_normalizer", c.keyNormalizerName, "default", "none")
+	switch c.keyNormalizerName {
+	case "default":
+		c.keyNormalizer = NewDefaultKeyNormalizer()
+	case "none":
+		c.keyNormalizer = NewNullKeyNormalizer()
+	}
+	c.maxKeySize = c.getAsInteger(config, "options.max_key_size", c.maxKeySize, 1, 250)
+	c.maxExpiration = int64(c.getAsInteger(config, "options.max_expiration", int(c.maxExpiration), 1, math.MaxInt32))
+	c.allowNoExpiration = c.getAsBoolean(config, "options.allow_no_expiration", c.allowNoExpiration)
+	c.maxValue = c.getAsInteger(config, "options.max_value", c.maxValue, 1, -1)
+	c.poolSize = c.getAsInteger(config, "options.pool_size", c.poolSize, 1, -1)
+	c.reconnect = c.getAsInteger(config, "options.reconnect", c.reconnect, 1, -1)
+	c.timeout = c.getAsInteger(config, "options.timeout", c.timeout, 1, -1)
+	c.retries = c.getAsInteger(config, "options.retries", c.retries, 0, -1)
+	c.failures = c.getAsInteger(config, "options.failures", c.failures, 1, -1)
+	c.retry = c.getAsInteger(config, "options.retry", c.retry, 0, -1)
+	c.idle = c.getAsInteger(config, "options.idle", c.idle, 0, -1)
+	c.remove = c.getAsBoolean(config, "options.remove", c.remove)
+	c.distribution = c.getAsEnum(config, "options.distribution", c.distribution,
+		distributionModulo, distributionKetama, distributionRendezvous)
+	c.sslCaFile = config.GetAsStringWithDefault("options.ssl_ca_file", c.sslCaFile)
+	c.sslCertFile = config.GetAsStringWithDefault("options.ssl_cert_file", c.sslCertFile)
+	c.sslKeyFile = config.GetAsStringWithDefault("options.ssl_key_file", c.sslKeyFile)
+	c.sslServerName = config.GetAsStringWithDefault("options.ssl_server_name", c.sslServerName)
+	c.sslInsecure = c.getAsBoolean(config, "options.ssl_insecure_skip_verify", c.sslInsecure)
+}
+
+// configureServerOrder remembers positions of servers in "connections" section by their indexes.
+// The connection resolver returns configuration sections in random order.
+func (c *MemcachedConnection) configureServerOrder(config *cconf.ConfigParams) {
+	sections := config.GetSection("connections")
+	names := sections.GetSectionNames()
+	sort.SliceStable(names, func(i, j int) bool {
+		left, leftErr := strconv.Atoi(names[i])
+		right, rightErr := strconv.Atoi(names[j])
+		if leftErr == nil && rightErr == nil {
+			return left < right
+		}
+		if leftErr == nil || rightErr == nil {
+			return leftErr == nil
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		address := c.serverAddress(ccon.NewConnectionParams(sections.GetSection(name).Value()))
+		if _, ok := c.serverOrder[address]; !ok {
+			c.serverOrder[address] = len(c.serverOrder)
+		}
+	}
+}
+
+func (c *MemcachedConnection) serverAddress(connection *ccon.ConnectionParams) string {
+	port := connection.Port()
+	if port == 0 {
+		port = defaultPort
+	}
+	return connection.Host() + ":" + strconv.Itoa(port)
+}
+
+func (c *MemcachedConnection) serverPosition(connection *ccon.ConnectionParams) int {
+	if position, ok := c.serverOrder[c.serverAddress(connection)]; ok {
+		return position
+	}
+	return len(c.serverOrder)
+}
+
+func (c *MemcachedConnection) getAsInteger(config *cconf.ConfigParams, key string, defaultValue int, min int, max int) int {
+	str, ok := config.GetAsNullableString(key)
+	if !ok {
+		return defaultValue
+	}
+
+	value, err := strconv.Atoi(str)
+	if err != nil || value < min || (max >= 0 && value > max) {
+		c.setConfigError(key, str)
+		return defaultValue
+	}
+	return value
+}
+
+func (c *MemcachedConnection) getAsBoolean(config *cconf.ConfigParams, key string, defaultValue bool) bool {
+	str, ok := config.GetAsNullableString(key)
+	if !ok {
+		return defaultValue
+	}
+
+	value, ok := config.GetAsNullableBoolean(key)
+	if !ok {
+		c.setConfigError(key, str)
+		return defaultValue
+	}
+	return value
+}
+
+func (c *MemcachedConnection) getAsEnum(config *cconf.ConfigParams, key string, defaultValue string, values ...string) string {
+	str, ok := config.GetAsNullableString(key)
+	if !ok {
+		return defaultValue
+	}
+
+	value := strings.ToLower(str)
+	for _, allowed := range values {
+		if value == allowed {
+			return value
+		}
+	}
+	c.setConfigError(key, str)
+	return defaultValue
+}
+
+func (c *MemcachedConnection) setConfigError(key string, value string) {
+	if c.configErr != nil {
+		return
+	}
+	c.configErr = cerr.NewConfigError("", "INVALID_OPTION", "Option "+key+" has invalid value "+value).
+		WithDetails("option", key).
+		WithDetails("value", value)
+}
+
+// SetReferences method are sets references to dependent components.
+//   - ctx context.Context
+//   - references 	references to locate the component dependencies.
+func (c *MemcachedConnection) SetReferences(ctx context.Context, references cref.IReferences) {
+	c.connectionResolver.SetReferences(ctx, references)
+	c.credentialResolver.SetReferences(ctx, references)
+
+	selector, ok := references.GetOneOptional(
+		cref.NewDescriptor("*", "server-selector", "*", "*", "1.0"),
+	).(IServerSelector)
+	if ok {
+		c.SetServerSelector(selector)
+	}
+}
+
+// IsOpen method are checks if the component is opened.
+// Returns: true if the component has been opened and false otherwise.
+func (c *MemcachedConnection) IsOpen() bool {
+	return c.client != nil
+}
+
+// Open method are opens the component.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *MemcachedConnection) Open(ctx context.Context, correlationId string) error {
+	if c.configErr != nil {
+		return c.configErr.WithCorrelationId(correlationId)
+	}
+
+	connections, err := c.connectionResolver.ResolveAll(correlationId)
+
+	if err == nil && len(connections) == 0 {
+		err = cerr.NewConfigError(correlationId, "NO_CONNECTION", "Connection is not configured")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Servers are put back in the configured order, discovered servers follow them
+	sort.SliceStable(connections, func(i, j int) bool {
+		return c.serverPosition(connections[i]) < c.serverPosition(connections[j])
+	})
+	servers := make([]*MemcachedServer, 0, len(connections))
+	tlsServers := map[string]string{}
+	for _, connection := range connections {
+		port := connection.Port()
+		if port == 0 {
+			port = defaultPort
+		}
+		weight := connection.GetAsIntegerWithDefault("weight", 1)
+		if weight <= 0 {
+			return cerr.NewConfigError(correlationId, "INVALID_WEIGHT", "Server weight shall be positive").
+				WithDetails("host", connection.Host()).
+				WithDetails("weight", weight)
+		}
+
+		server, err := NewMemcachedServer(connection.Host(), port, weight)
+		if err != nil {
+			return cerr.NewConnectionError(correlationId, "CONNECT_FAILED", "Failed to resolve Memcached servers").
+				WithCause(err)
+		}
+		servers = append(servers, server)
+		if strings.ToLower(connection.Protocol()) == protocolTls {
+			tlsServers[server.Addr.String()] = connection.Host()
+		}
+	}
+	tlsConfig, err := newTlsConfig(correlationId, c.sslCaFile, c.sslCertFile, c.sslKeyFile,
+		c.sslServerName, c.sslInsecure)
+	if err != nil {
+		return err
+	}
+
+	credential, err := c.credentialResolver.Lookup(ctx, correlationId)
+	if err != nil {
+		return err
+	}
+	c.username = ""
+	c.password = ""
+	if credential != nil {
+		c.username = credential.Username()
+		c.password = credential.Password()
+	}
+	if strings.ContainsAny(c.username, " \t\r\n") {
+		return cerr.NewConfigError(correlationId, "INVALID_USERNAME",
+			"User name "+c.username+" can not contain spaces in text protocol authentication").
+			WithDetails("username", c.username)
+	}
+
+	serverSelector := c.serverSelector
+	switch {
+	case serverSelector != nil:
+	case c.distribution == distributionKetama:
+		serverSelector = NewKetamaServerSelector()
+	case c.distribution == distributionRendezvous:
+		serverSelector = NewRendezvousServerSelector()
+	default:
+		serverSelector = NewModuloServerSelector()
+	}
+	// Ketama and rendezvous place keys by server names, and sorted servers make the rest of
+	// their choices the same on all instances. Modulo and custom selectors place keys
+	// by the configured order, so it is kept.
+	if c.serverSelector == nil && c.distribution != distributionModulo {
+		sort.SliceStable(servers, func(i, j int) bool {
+			if servers[i].Host != servers[j].Host {
+				return servers[i].Host < servers[j].Host
+			}
+			return servers[i].Port < servers[j].Port
+		})
+	}
+	selector, err := newHealthSelector(servers, serverSelector, c.failures, time.Duration(c.retry)*time.Millisecond, c.remove)
+	if err != nil {
+		return cerr.NewConfigError(correlationId, "INVALID_SERVERS", "Servers are not accepted by the server selector").
+			WithCause(err)
+	}
+
+	// Clients of single servers process replicas of keys
+	serverClients := map[string]*memcache.Client{}
+	for _, server := range servers {
+		serverClients[server.Addr.String()] = c.newClient(&fixedServerSelector{addr: server.Addr})
+	}
+
+	c.tlsConfig = tlsConfig
+	c.tlsServers = tlsServers
+	c.selector = selector
+	c.client = c.newClient(selector)
+	c.serverClients = serverClients
+
+	return nil
+}
+
+func (c *MemcachedConnection) newClient(selector memcache.ServerSelector) *memcache.Client {
+	client := memcache.NewFromSelector(selector)
+	client.Timeout = time.Duration(c.timeout) * time.Millisecond
+	client.MaxIdleConns = c.poolSize
+	client.DialContext = c.dial
+	return client
+}
+
+// Close method are closes component and frees used resources.
+// Parameters:
+//   - ctx context.Context
+//   - correlationId 	(optional) transaction id to trace execution through call chain.
+// Retruns: error or nil no errors occured.
+func (c *MemcachedConnection) Close(ctx context.Context, correlationId string) error {
+	if c.client != nil {
+		c.client.Close()
+	}
+	for _, client := range c.serverClients {
+		client.Close()
+	}
+	c.client = nil
+	c.serverClients = nil
+	c.selector = nil
+	return nil
+}
+
+// GetClient method are returns the underlying Memcached client.
+// Returns: the client or nil if the component is not opened.
+func (c *MemcachedConnection) GetClient() *memcache.Client {
+	return c.client
+}
+
+// Execute method are calls an action with the Memcached client and retries it
+// when it fails on a network error.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - action            an action to execute.
+// Returns: an error returned by the last attempt or nil for success.
+func (c *MemcachedConnection) Execute(correlationId string, action func(client *memcache.Client) error) error {
+	client := c.client
+	if client == nil {
+		return cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+	}
+
+	return c.wrapError(correlationId, c.execute(client, action, true))
+}
+
+// ExecuteOnce method are calls an action with the Memcached client that is not idempotent,
+// such as add, incr, decr, append, prepend or cas. A failed command may be already applied
+// by the server, so the action is retried only when a connection to the server was not established.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - action            an action to execute.
+// Returns: an error returned by the last attempt or nil for success.
+func (c *MemcachedConnection) ExecuteOnce(correlationId string, action func(client *memcache.Client) error) error {
+	client := c.client
+	if client == nil {
+		return cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+	}
+
+	return c.wrapError(correlationId, c.execute(client, action, false))
+}
+
+// execute calls an action and retries it on network errors. Actions that are not idempotent
+// are retried only when the connection failed before the command was sent.
+func (c *MemcachedConnection) execute(client *memcache.Client, action func(client *memcache.Client) error, idempotent bool) error {
+	var err error
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		err = action(client)
+		if err == nil || !isNetworkError(err) || !(idempotent || isDialError(err)) {
+			break
+		}
+	}
+	return err
+}
+
+// GroupKeys method are groups keys by servers they are stored on,
+// so batch operations can process each server separately.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - keys              Memcached keys to group.
+// Returns: keys grouped by server address and errors for keys that can not be mapped to a live server.
+func (c *MemcachedConnection) GroupKeys(correlationId string, keys []string) (map[string][]string, map[string]error) {
+	groups := map[string][]string{}
+	errs := map[string]error{}
+
+	selector := c.selector
+	if selector == nil {
+		err := cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+		for _, key := range keys {
+			errs[key] = err
+		}
+		return groups, errs
+	}
+
+	for _, key := range keys {
+		addr, err := selector.PickServer(key)
+		if err != nil {
+			errs[key] = c.wrapError(correlationId, err)
+			continue
+		}
+		groups[addr.String()] = append(groups[addr.String()], key)
+	}
+	return groups, errs
+}
+
+func (c *MemcachedConnection) wrapError(correlationId string, err error) error {
+	if errors.Is(err, errServerDead) || errors.Is(err, memcache.ErrNoServers) {
+		return cerr.NewConnectionError(correlationId, "NO_SERVERS", "No live Memcached servers to process the request").
+			WithCause(err)
+	}
+	return err
+}
+
+// MaxValue method are gets the maximum value length.
+func (c *MemcachedConnection) MaxValue() int {
+	return c.maxValue
+}
+
+// MaxExpiration method are gets the maximum expiration duration in seconds.
+func (c *MemcachedConnection) MaxExpiration() int64 {
+	return c.maxExpiration
+}
+
+// SetKeyNormalizer method are sets a policy to convert user keys into Memcached keys.
+//   - normalizer    a key normalizer to use.
+func (c *MemcachedConnection) SetKeyNormalizer(normalizer IKeyNormalizer) {
+	c.keyNormalizer = normalizer
+	c.keyNormalizerName = ""
+}
+
+// SetServerSelector method are sets an algorithm to distribute keys between servers.
+// It shall be called before the connection is opened.
+//   - selector    a server selector to use.
+func (c *MemcachedConnection) SetServerSelector(selector IServerSelector) {
+	c.serverSelector = selector
+	c.distribution = ""
+}
+
+// NormalizeKey method are converts a user key into Memcached key and validates the result.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a user key.
+// Returns: Memcached key or BadRequestError if the key can not be used.
+func (c *MemcachedConnection) NormalizeKey(correlationId string, key string) (string, error) {
+	if key == "" {
+		return "", cerr.NewBadRequestError(correlationId, "EMPTY_KEY", "Key can not be empty")
+	}
+	normalized := c.keyNormalizer.Normalize(c.keyPrefix+key, c.maxKeySize)
+	if err := c.CheckKey(correlationId, normalized); err != nil {
+		return "", err.(*cerr.ApplicationError).WithDetails("original_key", key)
+	}
+	return normalized, nil
+}
+
+// DeriveKey method are creates a key of an auxiliary item from a normalized key and a suffix.
+// The suffix shall contain only allowed characters. When the result is too long
+// the normalized key is replaced with its SHA-1.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a normalized key.
+//   - suffix            a suffix of the auxiliary item.
+// Returns: the auxiliary key or BadRequestError if it can not be created.
+func (c *MemcachedConnection) DeriveKey(correlationId string, key string, suffix string) (string, error) {
+	derived := key + suffix
+	if len(derived) > c.maxKeySize {
+		hash := sha1.Sum([]byte(key))
+		derived = hex.EncodeToString(hash[:]) + suffix
+	}
+	if err := c.CheckKey(correlationId, derived); err != nil {
+		return "", err
+	}
+	return derived, nil
+}
+
+// CheckKey method are validates a key against the maximum key size and allowed characters.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a key to check.
+// Returns: BadRequestError if the key is invalid or nil otherwise.
+func (c *MemcachedConnection) CheckKey(correlationId string, key string) error {
+	if key == "" {
+		return cerr.NewBadRequestError(correlationId, "EMPTY_KEY", "Key can not be empty")
+	}
+	if len(key) > c.maxKeySize {
+		return cerr.NewBadRequestError(correlationId, "KEY_TOO_LONG",
+			"Key length "+strconv.Itoa(len(key))+" exceeds maximum of "+strconv.Itoa(c.maxKeySize)).
+			WithDetails("key", key)
+	}
+	for i := 0; i < len(key); i++ {
+		if isIllegalKeyByte(key[i]) {
+			return cerr.NewBadRequestError(correlationId, "INVALID_KEY",
+				"Key contains illegal character at position "+strconv.Itoa(i)).
+				WithDetails("key", key)
+		}
+	}
+	return nil
+}
+
+// CheckValue method are validates a serialized value against the maximum value size.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a key of the value.
+//   - value             a serialized value to check.
+// Returns: BadRequestError if the value is too large or nil otherwise.
+func (c *MemcachedConnection) CheckValue(correlationId string, key string, value []byte) error {
+	if len(value) > c.maxValue {
+		return cerr.NewBadRequestError(correlationId, "VALUE_TOO_LARGE",
+			"Value size "+strconv.Itoa(len(value))+" exceeds maximum of "+strconv.Itoa(c.maxValue)).
+			WithDetails("key", key)
+	}
+	return nil
+}
+
+// Expiration method are converts a timeout in milliseconds into Memcached expiration.
+// Timeouts are rounded up to whole seconds and limited by the maximum expiration.
+// Expirations longer than 30 days are sent as absolute Unix time, as Memcached requires,
+// and the time is limited by the maximum 32-bit value.
+// Zero timeout keeps items until they are removed and is accepted only when
+// "options.allow_no_expiration" is enabled.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - timeout           expiration timeout in milliseconds.
+// Returns: expiration for Memcached commands or BadRequestError if the timeout is invalid.
+func (c *MemcachedConnection) Expiration(correlationId string, timeout int64) (int32, error) {
+	if timeout == NoExpiration {
+		if !c.allowNoExpiration {
+			return 0, cerr.NewBadRequestError(correlationId, "NO_EXPIRATION",
+				"Timeout 0 means no expiration that is disabled by options.allow_no_expiration")
+		}
+		return 0, nil
+	}
+	if timeout < 0 {
+		return 0, cerr.NewBadRequestError(correlationId, "INVALID_TIMEOUT", "Timeout can not be negative").
+			WithDetails("timeout", timeout)
+	}
+
+	seconds := (timeout + 999) / 1000
+	if seconds > c.maxExpiration {
+		seconds = c.maxExpiration
+	}
+	if seconds > maxRelativeExpiration {
+		seconds += time.Now().Unix()
+		// Memcached keeps expiration in 32 bits, longer times are reduced to its limit
+		if seconds > math.MaxInt32 {
+			seconds = math.MaxInt32
+		}
+	}
+	return int32(seconds), nil
+}
+
+func (c *MemcachedConnection) dial(ctx context.Context, network string, address string) (net.Conn, error) {
+	conn, err := c.dialServer(network, address)
+	if err != nil {
+		return nil, err
+	}
+	return newIdleConn(conn, c, network, address), nil
+}
+
+func (c *MemcachedConnection) dialServer(network string, address string) (net.Conn, error) {
+	dialer := net.Dialer{
+		Timeout: time.Duration(c.reconnect) * time.Millisecond,
+	}
+	conn, err := dialer.Dial(network, address)
+	if err == nil {
+		conn, err = c.handshake(conn, address)
+	}
+
+	if selector := c.selector; selector != nil {
+		if err == nil {
+			selector.markAlive(address)
+		} else if isNetworkError(err) {
+			selector.markFailed(address)
+		}
+	}
+	return conn, err
+}
+
+func (c *MemcachedConnection) handshake(conn net.Conn, address string) (net.Conn, error) {
+	serverName, secure := c.tlsServers[address]
+	if !secure && c.username == "" {
+		return conn, nil
+	}
+
+	conn.SetDeadline(time.Now().Add(time.Duration(c.timeout) * time.Millisecond))
+
+	if secure {
+		config := c.tlsConfig.Clone()
+		if config.ServerName == "" {
+			config.ServerName = serverName
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	if c.username != "" {
+		if err := authenticate(conn, c.username, c.password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (c *MemcachedConnection) markFailed(address string) {
+	if selector := c.selector; selector != nil {
+		selector.markFailed(address)
+	}
+}
+
+func (c *MemcachedConnection) markAlive(address string) {
+	if selector := c.selector; selector != nil {
+		selector.markAlive(address)
+	}
+}
+
+// isDialError checks if a connection to the server was not established, so no command was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	var timeoutErr *memcache.ConnectTimeoutError
+	return (errors.As(err, &opErr) && opErr.Op == "dial") || errors.As(err, &timeoutErr)
+}
+
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	var timeoutErr *memcache.ConnectTimeoutError
+	return errors.As(err, &netErr) || errors.As(err, &timeoutErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
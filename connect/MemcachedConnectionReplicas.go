@@ -0,0 +1,249 @@
+package connect
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+// fixedServerSelector sends all keys to a single server.
+type fixedServerSelector struct {
+	addr net.Addr
+}
+
+func (s *fixedServerSelector) PickServer(key string) (net.Addr, error) {
+	return s.addr, nil
+}
+
+func (s *fixedServerSelector) Each(f func(net.Addr) error) error {
+	return f(s.addr)
+}
+
+// replicaSet is a list of servers that keep replicas of a key with clients to access them.
+type replicaSet struct {
+	selector  *healthSelector
+	clients   map[string]*memcache.Client
+	addresses []string
+}
+
+// pickReplicas picks servers that keep replicas of a key.
+func (c *MemcachedConnection) pickReplicas(correlationId string, key string, replicas int) (*replicaSet, error) {
+	selector := c.selector
+	clients := c.serverClients
+	if selector == nil || clients == nil {
+		return nil, cerr.NewInvalidStateError(correlationId, "NOT_OPENED", "Connection is not opened")
+	}
+
+	servers, err := selector.PickReplicas(key, replicas)
+	if err != nil {
+		return nil, c.wrapError(correlationId, err)
+	}
+	addresses := make([]string, len(servers))
+	for index, server := range servers {
+		addresses[index] = server.Addr.String()
+	}
+	return &replicaSet{selector: selector, clients: clients, addresses: addresses}, nil
+}
+
+// primary returns the index of the first replica on a live server or -1 when all servers are dead.
+func (s *replicaSet) primary() int {
+	for index, address := range s.addresses {
+		if s.selector.isAvailable(address) {
+			return index
+		}
+	}
+	return -1
+}
+
+// quorum limits the required number of written replicas by the number of servers.
+func (s *replicaSet) quorum(quorum int, replicas int) int {
+	if quorum > replicas {
+		quorum = replicas
+	}
+	if quorum > len(s.selector.servers) {
+		quorum = len(s.selector.servers)
+	}
+	return quorum
+}
+
+// executeOn calls an action on a replica, replicas on dead servers fail without a request.
+func (c *MemcachedConnection) executeOn(set *replicaSet, index int, action func(client *memcache.Client) error,
+	idempotent bool) error {
+
+	address := set.addresses[index]
+	if !set.selector.isAvailable(address) {
+		return errServerDead
+	}
+	return c.execute(set.clients[address], action, idempotent)
+}
+
+// ExecuteRead method are calls a read action on replicas of a key in order of preference until it succeeds.
+// Replicas on dead servers are skipped, and the next replica is tried when the action
+// returns memcache.ErrCacheMiss or fails.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a Memcached key.
+//   - replicas          a number of servers that keep replicas of the key.
+//   - action            an action to execute.
+// Returns: nil when the action succeeded on a replica, memcache.ErrCacheMiss when
+// the key is missing on available replicas, or the last error.
+func (c *MemcachedConnection) ExecuteRead(correlationId string, key string, replicas int,
+	action func(client *memcache.Client) error) error {
+
+	set, err := c.pickReplicas(correlationId, key, replicas)
+	if err != nil {
+		return err
+	}
+
+	missed := false
+	err = errServerDead
+	for _, address := range set.addresses {
+		if !set.selector.isAvailable(address) {
+			continue
+		}
+		err = c.execute(set.clients[address], action, true)
+		if err == nil {
+			return nil
+		}
+		if err == memcache.ErrCacheMiss {
+			missed = true
+		}
+	}
+	if missed {
+		return memcache.ErrCacheMiss
+	}
+	return c.wrapError(correlationId, err)
+}
+
+// ExecutePrimary method are calls an action on the primary replica of a key,
+// that is the first replica on a live server.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a Memcached key.
+//   - replicas          a number of servers that keep replicas of the key.
+//   - action            an action to execute.
+// Returns: an error returned by the last attempt or nil for success.
+func (c *MemcachedConnection) ExecutePrimary(correlationId string, key string, replicas int,
+	action func(client *memcache.Client) error) error {
+
+	set, err := c.pickReplicas(correlationId, key, replicas)
+	if err != nil {
+		return err
+	}
+	primary := set.primary()
+	if primary < 0 {
+		return c.wrapError(correlationId, errServerDead)
+	}
+	return c.wrapError(correlationId, c.executeOn(set, primary, action, true))
+}
+
+// ExecuteWrite method are calls a write action on all replicas of a key concurrently.
+// The write succeeds when the action succeeded on the quorum of replicas.
+// The quorum is reduced to the number of servers when there are fewer servers than replicas.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a Memcached key.
+//   - replicas          a number of servers that keep replicas of the key.
+//   - quorum            a number of replicas that shall be written.
+//   - action            an action to execute.
+// Returns: nil when the quorum is reached, otherwise memcache.ErrCacheMiss, ErrNotStored or ErrCASConflict
+// returned by a replica, or ConnectionError with the first failure.
+func (c *MemcachedConnection) ExecuteWrite(correlationId string, key string, replicas int, quorum int,
+	action func(client *memcache.Client) error) error {
+
+	set, err := c.pickReplicas(correlationId, key, replicas)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(set.addresses))
+	c.executeAll(set, errs, -1, action, true)
+	return c.checkQuorum(correlationId, key, errs, set.quorum(quorum, replicas), true)
+}
+
+// ExecutePrimaryWrite method are calls a conditional write action on the primary replica of a key,
+// and when it succeeds calls a replicate action on other replicas concurrently.
+// The primary replica decides the outcome of conditional commands like add or cas,
+// so replicas are never written by commands that failed on the primary.
+// The actions are not retried after the connection was established, as ExecuteOnce.
+// Parameters:
+//   - correlationId     (optional) transaction id to trace execution through call chain.
+//   - key               a Memcached key.
+//   - replicas          a number of servers that keep replicas of the key.
+//   - quorum            a number of replicas that shall be written.
+//   - action            a conditional action to execute on the primary replica.
+//   - replicate         an action to copy the result to other replicas.
+// Returns: an error of the primary replica, nil when the quorum is reached, or ConnectionError
+// with the first failure when the primary replica was written and the quorum was not reached.
+func (c *MemcachedConnection) ExecutePrimaryWrite(correlationId string, key string, replicas int, quorum int,
+	action func(client *memcache.Client) error, replicate func(client *memcache.Client) error) error {
+
+	set, err := c.pickReplicas(correlationId, key, replicas)
+	if err != nil {
+		return err
+	}
+	primary := set.primary()
+	if primary < 0 {
+		return c.wrapError(correlationId, errServerDead)
+	}
+	if err := c.executeOn(set, primary, action, false); err != nil {
+		return c.wrapError(correlationId, err)
+	}
+
+	errs := make([]error, len(set.addresses))
+	c.executeAll(set, errs, primary, replicate, false)
+	return c.checkQuorum(correlationId, key, errs, set.quorum(quorum, replicas), false)
+}
+
+// executeAll calls an action on all replicas except the skipped one concurrently and collects errors.
+func (c *MemcachedConnection) executeAll(set *replicaSet, errs []error, skip int,
+	action func(client *memcache.Client) error, idempotent bool) {
+
+	var wg sync.WaitGroup
+	for index := range set.addresses {
+		if index == skip {
+			continue
+		}
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			errs[index] = c.executeOn(set, index, action, idempotent)
+		}(index)
+	}
+	wg.Wait()
+}
+
+// checkQuorum counts written replicas. When the quorum is not reached and results are returned,
+// results of commands like memcache.ErrNotStored are returned as is, otherwise ConnectionError is returned.
+func (c *MemcachedConnection) checkQuorum(correlationId string, key string, errs []error, quorum int, results bool) error {
+	succeeded := 0
+	var failure error
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		if failure == nil {
+			failure = err
+		}
+	}
+	if succeeded >= quorum {
+		return nil
+	}
+
+	// Results of commands are returned as is, so callers can tell them from failures
+	for _, err := range errs {
+		if results && (err == memcache.ErrCacheMiss || err == memcache.ErrNotStored || err == memcache.ErrCASConflict) {
+			return err
+		}
+	}
+	return cerr.NewConnectionError(correlationId, "QUORUM_NOT_REACHED",
+		"Only "+strconv.Itoa(succeeded)+" of "+strconv.Itoa(quorum)+" required replicas of "+key+" were written").
+		WithDetails("key", key).
+		WithDetails("succeeded", succeeded).
+		WithDetails("quorum", quorum).
+		WithCause(failure)
+}
@@ -0,0 +1,52 @@
+package connect
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// defaultPort is a default port of Memcached servers.
+const defaultPort = 11211
+
+// MemcachedServer is a Memcached server with its resolved address and weight in key distribution.
+type MemcachedServer struct {
+	// Host name or IP address as it is configured
+	Host string
+	// Port number
+	Port int
+	// Relative share of keys stored on the server
+	Weight int
+	// Resolved address used to connect to the server
+	Addr net.Addr
+}
+
+// NewMemcachedServer creates a new server and resolves its address.
+//   - host      host name or IP address.
+//   - port      port number.
+//   - weight    relative share of keys stored on the server.
+// Returns: the server or error when its address can not be resolved.
+func NewMemcachedServer(host string, port int, weight int) (*MemcachedServer, error) {
+	server := host + ":" + strconv.Itoa(port)
+
+	var addr net.Addr
+	var err error
+	if strings.Contains(server, "/") {
+		addr, err = net.ResolveUnixAddr("unix", server)
+	} else {
+		addr, err = net.ResolveTCPAddr("tcp", server)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemcachedServer{Host: host, Port: port, Weight: weight, Addr: addr}, nil
+}
+
+// Name returns host and port of the server, the port is omitted when it is default.
+func (s *MemcachedServer) Name() string {
+	if s.Port == defaultPort {
+		return s.Host
+	}
+	return s.Host + ":" + strconv.Itoa(s.Port)
+}
@@ -0,0 +1,50 @@
+package connect
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+)
+
+const protocolTls = "tls"
+
+// newTlsConfig creates TLS configuration from CA bundle and client certificate files.
+// Empty file names are skipped, so system root CAs are used when no CA bundle is set.
+func newTlsConfig(correlationId string, caFile string, certFile string, keyFile string,
+	serverName string, insecure bool) (*tls.Config, error) {
+
+	config := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: insecure,
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, cerr.NewConfigError(correlationId, "SSL_CA_FILE", "Failed to read CA file "+caFile).
+				WithCause(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, cerr.NewConfigError(correlationId, "SSL_CA_FILE", "No certificates found in CA file "+caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, cerr.NewConfigError(correlationId, "SSL_CERT_FILE",
+				"Both ssl_cert_file and ssl_key_file shall be set for client certificate")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, cerr.NewConfigError(correlationId, "SSL_CERT_FILE", "Failed to load client certificate "+certFile).
+				WithCause(err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,63 @@
+package connect
+
+import (
+	"hash/crc32"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// ModuloServerSelector maps keys to servers by CRC32 of a key modulo the number of servers,
+// the same way as memcache.ServerList. Weights are ignored.
+// Changes of the server list remap most of the keys.
+type ModuloServerSelector struct {
+	mtx     sync.RWMutex
+	servers []*MemcachedServer
+}
+
+// NewModuloServerSelector creates a new instance of the selector.
+func NewModuloServerSelector() *ModuloServerSelector {
+	return &ModuloServerSelector{}
+}
+
+// SetServers sets servers to distribute keys between.
+func (c *ModuloServerSelector) SetServers(servers []*MemcachedServer) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.servers = servers
+	return nil
+}
+
+// PickServer returns the server that a given key is stored on.
+func (c *ModuloServerSelector) PickServer(key string) (*MemcachedServer, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if len(c.servers) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	if len(c.servers) == 1 {
+		return c.servers[0], nil
+	}
+	return c.servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(c.servers))], nil
+}
+
+// PickServers returns the server of a given key followed by the next servers of the list.
+func (c *ModuloServerSelector) PickServers(key string, count int) ([]*MemcachedServer, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if len(c.servers) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+	if count > len(c.servers) {
+		count = len(c.servers)
+	}
+	start := crc32.ChecksumIEEE([]byte(key)) % uint32(len(c.servers))
+	servers := make([]*MemcachedServer, count)
+	for index := range servers {
+		servers[index] = c.servers[(int(start)+index)%len(c.servers)]
+	}
+	return servers, nil
+}
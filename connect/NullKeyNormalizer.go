@@ -0,0 +1,15 @@
+package connect
+
+// NullKeyNormalizer keeps keys unchanged.
+// Illegal keys are rejected by key validation.
+type NullKeyNormalizer struct{}
+
+// NewNullKeyNormalizer creates a new instance of the normalizer.
+func NewNullKeyNormalizer() *NullKeyNormalizer {
+	return &NullKeyNormalizer{}
+}
+
+// Normalize returns the key as it is.
+func (c *NullKeyNormalizer) Normalize(key string, maxKeySize int) string {
+	return key
+}
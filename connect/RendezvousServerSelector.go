@@ -0,0 +1,117 @@
+package connect
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/bradfitz/gomemcache/memcache"
+)
+
+// RendezvousServerSelector maps keys to servers with highest random weight (rendezvous) hashing.
+// Every server gets a pseudo-random score for a key, and the key is stored on the server with the highest score,
+// so keys are distributed evenly and changes of the server list remap only keys of added or removed servers.
+// Scores are scaled by server weights with logarithmic method. Picking a server takes time
+// proportional to the number of servers.
+type RendezvousServerSelector struct {
+	mtx     sync.RWMutex
+	servers []*MemcachedServer
+	hashes  []uint64
+}
+
+// NewRendezvousServerSelector creates a new instance of the selector.
+func NewRendezvousServerSelector() *RendezvousServerSelector {
+	return &RendezvousServerSelector{}
+}
+
+// SetServers sets servers to distribute keys between.
+func (c *RendezvousServerSelector) SetServers(servers []*MemcachedServer) error {
+	hashes := make([]uint64, len(servers))
+	for index, server := range servers {
+		hashes[index] = hashString(server.Name())
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.servers = servers
+	c.hashes = hashes
+	return nil
+}
+
+// PickServer returns the server with the highest score for the key.
+func (c *RendezvousServerSelector) PickServer(key string) (*MemcachedServer, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if len(c.servers) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+
+	keyHash := hashString(key)
+	var result *MemcachedServer
+	maxScore := math.Inf(-1)
+	for index, server := range c.servers {
+		score := c.score(keyHash, index)
+		if score > maxScore {
+			maxScore = score
+			result = server
+		}
+	}
+	return result, nil
+}
+
+// PickServers returns servers with the highest scores for the key in order of their scores.
+func (c *RendezvousServerSelector) PickServers(key string, count int) ([]*MemcachedServer, error) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+
+	if len(c.servers) == 0 {
+		return nil, memcache.ErrNoServers
+	}
+
+	keyHash := hashString(key)
+	scores := make([]float64, len(c.servers))
+	indexes := make([]int, len(c.servers))
+	for index := range c.servers {
+		scores[index] = c.score(keyHash, index)
+		indexes[index] = index
+	}
+	// Stable sort keeps the server picked by PickServer first on equal scores
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+
+	if count > len(indexes) {
+		count = len(indexes)
+	}
+	servers := make([]*MemcachedServer, count)
+	for position := range servers {
+		servers[position] = c.servers[indexes[position]]
+	}
+	return servers, nil
+}
+
+// score returns a weighted score of the server with a given index for the key hash.
+func (c *RendezvousServerSelector) score(keyHash uint64, index int) float64 {
+	// Uniform value in (0, 1) from the combined hash
+	hash := mixHash(keyHash ^ c.hashes[index])
+	uniform := (float64(hash>>11) + 0.5) / (1 << 53)
+	return -float64(c.servers[index].Weight) / math.Log(uniform)
+}
+
+func hashString(value string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	return hash.Sum64()
+}
+
+// mixHash is a SplitMix64 finalizer that spreads bits of combined hashes.
+func mixHash(hash uint64) uint64 {
+	hash ^= hash >> 30
+	hash *= 0xbf58476d1ce4e5b9
+	hash ^= hash >> 27
+	hash *= 0x94d049bb133111eb
+	hash ^= hash >> 31
+	return hash
+}
@@ -3,5 +3,6 @@ package memcached
 import (
 	_ "github.com/pip-services3-gox/pip-services3-memcached-gox/build"
 	_ "github.com/pip-services3-gox/pip-services3-memcached-gox/cache"
+	_ "github.com/pip-services3-gox/pip-services3-memcached-gox/connect"
 	_ "github.com/pip-services3-gox/pip-services3-memcached-gox/lock"
 )
@@ -2,21 +2,21 @@ package lock
 
 import (
 	"context"
-	"strconv"
-	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
 	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
 	cref "github.com/pip-services3-gox/pip-services3-commons-gox/refer"
-	ccon "github.com/pip-services3-gox/pip-services3-components-gox/connect"
 	clock "github.com/pip-services3-gox/pip-services3-components-gox/lock"
+	memcon "github.com/pip-services3-gox/pip-services3-memcached-gox/connect"
 )
 
 /*
 MemcachedLock are distributed lock that implemented based on Memcaches caching service.
 
-The current implementation does not support authentication.
+Connections with "tls" protocol are encrypted using TLS. When credentials are configured
+every connection is authenticated using text protocol authentication,
+see MemcachedConnection for details.
 
 Configuration parameters:
 
@@ -25,22 +25,41 @@ Configuration parameters:
   - host:                  host name or IP address
   - port:                  port number
   - uri:                   resource URI or connection string with all parameters in it
+  - protocol:              (optional) "tls" to encrypt the connection
+  - weight:                (optional) relative share of keys stored on the server with ketama and rendezvous distribution (default: 1)
+- credential(s):
+  - store_key:             (optional) a key to retrieve the credentials from ICredentialStore
+  - username:              user name
+  - password:              user password
 - options:
-  - max_size:              maximum number of values stored in this cache (default: 1000)
+  - retry_timeout:         timeout in milliseconds to retry lock acquisition (default: 100)
+  - ssl_ca_file:           (optional) CA bundle file to verify server certificates
+  - ssl_cert_file:         (optional) client certificate file
+  - ssl_key_file:          (optional) client private key file
+  - ssl_server_name:       (optional) server name to verify, by default the connection host is used
+  - ssl_insecure_skip_verify: skip verification of server certificates (default: false)
+  - key_prefix:            (optional) prefix added to all keys, for instance "myservice:"
+  - key_normalizer:        key policy: "default" to escape illegal characters and hash long keys, "none" to keep keys unchanged (default: default)
   - max_key_size:          maximum key length (default: 250)
-  - max_expiration:        maximum expiration duration in milliseconds (default: 2592000)
-  - max_value:             maximum value length (default: 1048576)
-  - pool_size:             pool size (default: 5)
-  - reconnect:             reconnection timeout in milliseconds (default: 10 sec)
-  - retries:               number of retries (default: 3)
-  - timeout:               default caching timeout in milliseconds (default: 1 minute)
-    - failures:              number of failures before stop retrying (default: 5)
-    - retry:                 retry timeout in milliseconds (default: 30 sec)
-    - idle:                  idle timeout before disconnect in milliseconds (default: 5 sec)
+  - max_expiration:        maximum expiration duration in seconds up to 2147483647, longer timeouts are reduced to it (default: 2592000)
+  - allow_no_expiration:   accept timeout 0 to keep items until they are removed (default: false)
+  - pool_size:             maximum number of idle connections kept per server (default: 5)
+  - reconnect:             timeout to establish a connection in milliseconds (default: 10 sec)
+  - timeout:               socket read/write timeout in milliseconds (default: 5 sec)
+  - retries:               number of retries of an operation failed on a network error, commands that are not idempotent are retried only when the connection failed (default: 3)
+  - failures:              number of consecutive failures before a server is marked as dead (default: 5)
+  - retry:                 time a dead server stays out of service in milliseconds (default: 30 sec)
+  - remove:                redistribute keys of dead servers to live ones (default: false)
+  - distribution:          key distribution between servers: "modulo", "ketama" or "rendezvous" (default: modulo)
+  - idle:                  idle timeout before a pooled connection is reestablished in milliseconds, 0 to disable (default: 5 sec)
+
+Invalid option values are reported as ConfigError when the lock is opened.
 
 References:
 
-- *:discovery:*:*:1.0        (optional) IDiscovery services to resolve connection
+- *:discovery:*:*:1.0         (optional) IDiscovery services to resolve connection
+- *:credential-store:*:*:1.0  (optional) Credential stores to resolve credentials
+- *:server-selector:*:*:1.0   (optional) IServerSelector to distribute keys between servers instead of options.distribution
 
 Example:
 	ctx := context.Background()
@@ -65,37 +84,13 @@ Example:
 */
 type MemcachedLock struct {
 	*clock.Lock
-	connectionResolver *ccon.ConnectionResolver
-	// maxKeySize         int
-	// maxExpiration      int64
-	// maxValue           int64
-	// poolSize           int
-	// reconnect          int
-	timeout int
-	// retries            int
-	// failures           int
-	// retry              int
-	remove bool
-	//idle   int
-	client *memcache.Client
+	connection *memcon.MemcachedConnection
 }
 
 // NewMemcachedLock method are creates a new instance of this lock.
 func NewMemcachedLock() *MemcachedLock {
 	c := &MemcachedLock{
-		connectionResolver: ccon.NewEmptyConnectionResolver(),
-		// maxKeySize:         250,
-		// maxExpiration:      2592000,
-		// maxValue:           1048576,
-		// poolSize:           5,
-		// reconnect:          10000,
-		timeout: 5000,
-		// retries:            5,
-		// failures:           5,
-		// retry:              30000,
-		remove: false,
-		//idle:   5000,
-		client: nil,
+		connection: memcon.NewMemcachedConnection(),
 	}
 	c.Lock = clock.InheritLock(c)
 	return c
@@ -107,32 +102,20 @@ func NewMemcachedLock() *MemcachedLock {
 func (c *MemcachedLock) Configure(ctx context.Context, config *cconf.ConfigParams) {
 	c.Lock.Configure(ctx, config)
 
-	c.connectionResolver.Configure(ctx, config)
-
-	// c.maxKeySize = config.GetAsIntegerWithDefault("options.max_key_size", c.maxKeySize)
-	// c.maxExpiration = config.GetAsLongWithDefault("options.max_expiration", c.maxExpiration)
-	// c.maxValue = config.GetAsLongWithDefault("options.max_value", c.maxValue)
-	// c.poolSize = config.GetAsIntegerWithDefault("options.pool_size", c.poolSize)
-	// c.reconnect = config.GetAsIntegerWithDefault("options.reconnect", c.reconnect)
-	c.timeout = config.GetAsIntegerWithDefault("options.timeout", c.timeout)
-	// c.retries = config.GetAsIntegerWithDefault("options.retries", c.retries)
-	// c.failures = config.GetAsIntegerWithDefault("options.failures", c.failures)
-	// c.retry = config.GetAsIntegerWithDefault("options.retry", c.retry)
-	// c.remove = config.GetAsBooleanWithDefault("options.remove", c.remove)
-	//c.idle = config.GetAsIntegerWithDefault("options.idle", c.idle)
+	c.connection.Configure(ctx, config)
 }
 
 // SetReferences method are sets references to dependent components.
 //   - ctx context.Context
 //   - references 	references to locate the component dependencies.
 func (c *MemcachedLock) SetReferences(ctx context.Context, references cref.IReferences) {
-	c.connectionResolver.SetReferences(ctx, references)
+	c.connection.SetReferences(ctx, references)
 }
 
 // IsOpen method are checks if the component is opened.
 // Returns: true if the component has been opened and false otherwise.
 func (c *MemcachedLock) IsOpen() bool {
-	return c.client != nil
+	return c.connection.IsOpen()
 }
 
 /// Open method are opens the component.
@@ -141,46 +124,7 @@ func (c *MemcachedLock) IsOpen() bool {
 //   - correlationId 	(optional) transaction id to trace execution through call chain.
 // Retruns: error or nil no errors occured.
 func (c *MemcachedLock) Open(ctx context.Context, correlationId string) error {
-	connections, err := c.connectionResolver.ResolveAll(correlationId)
-
-	if err == nil && len(connections) == 0 {
-		err = cerr.NewConfigError(correlationId, "NO_CONNECTION", "Connection is not configured")
-	}
-
-	if err != nil {
-		return err
-	}
-
-	var servers []string = make([]string, 0)
-	for _, connection := range connections {
-		host := connection.Host()
-		port := connection.Port()
-		if port == 0 {
-			port = 11211
-		}
-
-		servers = append(servers, host+":"+strconv.FormatInt(int64(port), 10))
-	}
-
-	// options = {
-	//     maxKeySize: c.maxKeySize,
-	//     maxExpiration: c.maxExpiration,
-	//     maxValue: c.maxValue,
-	//     poolSize: c.poolSize,
-	//     reconnect: c.reconnect,
-	//     timeout: c.timeout,
-	//     retries: c.retries,
-	//     failures: c.failures,
-	//     retry: c.retry,
-	//     remove: c.remove,
-	//     idle: c.idle
-	// };
-
-	c.client = memcache.New(servers...)
-	c.client.Timeout = time.Duration(c.timeout) * time.Millisecond
-	//c.client.MaxIdleConns = c.idle
-
-	return nil
+	return c.connection.Open(ctx, correlationId)
 }
 
 // Close method are closes component and frees used resources.
@@ -189,8 +133,7 @@ func (c *MemcachedLock) Open(ctx context.Context, correlationId string) error {
 //   - correlationId 	(optional) transaction id to trace execution through call chain.
 //   - callback 			callback function that receives error or nil no errors occured.
 func (c *MemcachedLock) Close(ctx context.Context, correlationId string) error {
-	c.client = nil
-	return nil
+	return c.connection.Close(ctx, correlationId)
 }
 
 func (c *MemcachedLock) checkOpened(correlationId string) (state bool, err error) {
@@ -216,14 +159,25 @@ func (c *MemcachedLock) TryAcquireLock(ctx context.Context, correlationId string
 	if !state {
 		return false, err
 	}
+	key, err = c.connection.NormalizeKey(correlationId, key)
+	if err != nil {
+		return false, err
+	}
+
+	expiration, err := c.connection.Expiration(correlationId, ttl)
+	if err != nil {
+		return false, err
+	}
 
-	lifetimeInSec := ttl / 1000
 	item := memcache.Item{
 		Key:        key,
 		Value:      []byte("lock"),
-		Expiration: int32(lifetimeInSec),
+		Expiration: expiration,
 	}
-	err = c.client.Add(&item)
+	// The lock can be added by a failed attempt, so a retry would find it taken
+	err = c.connection.ExecuteOnce(correlationId, func(client *memcache.Client) error {
+		return client.Add(&item)
+	})
 
 	if err != nil && err == memcache.ErrNotStored {
 		return false, nil
@@ -242,7 +196,13 @@ func (c *MemcachedLock) ReleaseLock(ctx context.Context, correlationId string, k
 	if !state {
 		return err
 	}
-	err = c.client.Delete(key)
+	key, err = c.connection.NormalizeKey(correlationId, key)
+	if err != nil {
+		return err
+	}
+	err = c.connection.Execute(correlationId, func(client *memcache.Client) error {
+		return client.Delete(key)
+	})
 	if err != nil && err == memcache.ErrCacheMiss {
 		err = nil
 	}
@@ -0,0 +1,136 @@
+package test_cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memcache "github.com/pip-services3-gox/pip-services3-memcached-gox/cache"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheRetrieveWithState(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[any](t, stub, "options.negative_ttl", 10000)
+	defer cache.Close(ctx, "")
+
+	result, err := cache.RetrieveWithState(ctx, "", "missing")
+	assert.Nil(t, err)
+	assert.Equal(t, memcache.CacheStateMiss, result.State)
+
+	// Stored nil is a hit
+	_, err = cache.Store(ctx, "", "nil", nil, 5000)
+	assert.Nil(t, err)
+	result, err = cache.RetrieveWithState(ctx, "", "nil")
+	assert.Nil(t, err)
+	assert.Equal(t, memcache.CacheStateHit, result.State)
+	assert.Nil(t, result.Value)
+
+	err = cache.StoreAbsent(ctx, "", "absent")
+	assert.Nil(t, err)
+	assertExpiresIn(t, stub, "absent", 10*1000*1000*1000)
+	result, err = cache.RetrieveWithState(ctx, "", "absent")
+	assert.Nil(t, err)
+	assert.Equal(t, memcache.CacheStateAbsent, result.State)
+
+	// Absent values look missing to other methods
+	val, err := cache.Retrieve(ctx, "", "absent")
+	assert.Nil(t, err)
+	assert.Nil(t, val)
+	assert.False(t, cache.Contains(ctx, "", "absent"))
+	values, missing, err := cache.RetrieveMany(ctx, "", []string{"absent", "nil"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"absent"}, missing)
+	assert.Len(t, values, 1)
+
+	// Stored values replace absent markers
+	_, err = cache.Store(ctx, "", "absent", "value", 5000)
+	assert.Nil(t, err)
+	result, err = cache.RetrieveWithState(ctx, "", "absent")
+	assert.Nil(t, err)
+	assert.Equal(t, memcache.CacheStateHit, result.State)
+	assert.Equal(t, "value", result.Value)
+}
+
+func TestMemcachedCacheNegativeLoads(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub, "options.negative_ttl", 10000)
+	defer cache.Close(ctx, "")
+
+	var calls int32
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "", cerr.NewNotFoundError("", "USER_NOT_FOUND", "User is not found")
+	}
+
+	_, err = cache.GetOrLoad(ctx, "", "user1", loader, 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, "USER_NOT_FOUND", err.(*cerr.ApplicationError).Code)
+
+	// Absent values do not call the loader again
+	result, err := cache.GetOrLoadWithState(ctx, "", "user1", loader, 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.NotFound, err.(*cerr.ApplicationError).Category)
+	assert.Equal(t, memcache.CacheStateAbsent, result.State)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Negative caching is disabled by default
+	disabled := newStubCache[string](t, stub)
+	defer disabled.Close(ctx, "")
+	for index := 0; index < 2; index++ {
+		_, err = disabled.GetOrLoad(ctx, "", "user2", loader, 5000)
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestMemcachedCacheSlidingAbsent(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub,
+		"options.negative_ttl", 10000,
+		"options.sliding_expiration", true,
+		"options.sliding_timeout", 600000,
+	)
+	defer cache.Close(ctx, "")
+
+	// Sliding reads do not extend absent markers
+	err = cache.StoreAbsent(ctx, "", "absent")
+	assert.Nil(t, err)
+	for index := 0; index < 2; index++ {
+		result, err := cache.RetrieveWithState(ctx, "", "absent")
+		assert.Nil(t, err)
+		assert.Equal(t, memcache.CacheStateAbsent, result.State)
+		assertExpiresIn(t, stub, "absent", 10*time.Second)
+	}
+
+	_, err = cache.GetOrLoad(ctx, "", "absent", func(ctx context.Context) (string, error) {
+		return "value", nil
+	}, 5000)
+	assert.NotNil(t, err)
+	assertExpiresIn(t, stub, "absent", 10*time.Second)
+
+	// Values are still extended
+	_, err = cache.Store(ctx, "", "key1", "value1", 5000)
+	assert.Nil(t, err)
+	_, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assertExpiresIn(t, stub, "key1", 600*time.Second)
+}
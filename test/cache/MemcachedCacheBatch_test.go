@@ -0,0 +1,105 @@
+package test_cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
+	memcache "github.com/pip-services3-gox/pip-services3-memcached-gox/cache"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func newClusterCacheUnopened[T any](stubs []*memfixture.MemcachedStub, tuples ...any) *memcache.MemcachedCache[T] {
+	cache := memcache.NewMemcachedCache[T]()
+	config := cconf.NewEmptyConfigParams()
+	for index, stub := range stubs {
+		config.Put("connections."+strconv.Itoa(index)+".host", stub.Host())
+		config.Put("connections."+strconv.Itoa(index)+".port", stub.Port())
+	}
+	config = config.Override(cconf.NewConfigParamsFromTuples(tuples...))
+	cache.Configure(context.Background(), config)
+	return cache
+}
+
+func newClusterCache[T any](t *testing.T, stubs []*memfixture.MemcachedStub, tuples ...any) *memcache.MemcachedCache[T] {
+	cache := newClusterCacheUnopened[T](stubs, tuples...)
+	err := cache.Open(context.Background(), "")
+	assert.Nil(t, err)
+	return cache
+}
+
+func TestMemcachedCacheBatch(t *testing.T) {
+	ctx := context.Background()
+
+	stub1, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub1.Close()
+	stub2, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub2.Close()
+
+	cache := newClusterCache[string](t, []*memfixture.MemcachedStub{stub1, stub2}, "options.retries", 0)
+	defer cache.Close(ctx, "")
+
+	items := []memcache.CacheItem[string]{}
+	keys := []string{}
+	for index := 0; index < 20; index++ {
+		key := "key" + strconv.Itoa(index)
+		keys = append(keys, key)
+		items = append(items, memcache.NewCacheItem(key, "value"+strconv.Itoa(index), 5000))
+	}
+
+	err = cache.StoreMany(ctx, "", items)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, stub1.Keys())
+	assert.NotEmpty(t, stub2.Keys())
+	assert.Len(t, append(stub1.Keys(), stub2.Keys()...), 20)
+
+	values, missing, err := cache.RetrieveMany(ctx, "", append(keys, "missing1", "missing2"))
+	assert.Nil(t, err)
+	assert.Len(t, values, 20)
+	assert.Equal(t, "value7", values["key7"])
+	assert.Equal(t, []string{"missing1", "missing2"}, missing)
+
+	err = cache.RemoveMany(ctx, "", keys[:10])
+	assert.Nil(t, err)
+	values, missing, err = cache.RetrieveMany(ctx, "", keys)
+	assert.Nil(t, err)
+	assert.Len(t, values, 10)
+	assert.Equal(t, keys[:10], missing)
+
+	// Keys of a failed server are reported separately
+	stub2Keys := map[string]bool{}
+	for _, key := range stub2.Keys() {
+		stub2Keys[key] = true
+	}
+	stub2.Close()
+
+	values, missing, err = cache.RetrieveMany(ctx, "", keys[10:])
+	assert.NotNil(t, err)
+	batchErr, ok := err.(*memcache.CacheBatchError)
+	assert.True(t, ok)
+	assert.Len(t, batchErr.Errors, len(stub2Keys))
+	for key := range batchErr.Errors {
+		assert.True(t, stub2Keys[key])
+	}
+	assert.Len(t, values, 10-len(stub2Keys))
+	assert.Empty(t, missing)
+
+	// Invalid keys do not fail the whole batch
+	cache = newClusterCache[string](t, []*memfixture.MemcachedStub{stub1}, "options.key_normalizer", "none")
+	defer cache.Close(ctx, "")
+	err = cache.StoreMany(ctx, "", []memcache.CacheItem[string]{
+		memcache.NewCacheItem("valid", "value1", 5000),
+		memcache.NewCacheItem("in valid", "value2", 1000),
+	})
+	assert.NotNil(t, err)
+	batchErr = err.(*memcache.CacheBatchError)
+	assert.Len(t, batchErr.Errors, 1)
+	assert.NotNil(t, batchErr.Errors["in valid"])
+	val, err := cache.Retrieve(ctx, "", "valid")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+}
@@ -0,0 +1,156 @@
+package test_cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub)
+	defer cache.Close(ctx, "")
+
+	val, cas, err := cache.RetrieveWithCas(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+	assert.Equal(t, uint64(0), cas)
+
+	// Zero token stores only missing values
+	ok, err := cache.CompareAndSwap(ctx, "", "key1", "value1", 0, 5000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = cache.CompareAndSwap(ctx, "", "key1", "value2", 0, 5000)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	val, cas, err = cache.RetrieveWithCas(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	assert.NotEqual(t, uint64(0), cas)
+
+	// Concurrent change invalidates the token
+	_, err = cache.Store(ctx, "", "key1", "value3", 5000)
+	assert.Nil(t, err)
+	ok, err = cache.CompareAndSwap(ctx, "", "key1", "value4", cas, 5000)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	val, cas, err = cache.RetrieveWithCas(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value3", val)
+	ok, err = cache.CompareAndSwap(ctx, "", "key1", "value4", cas, 5000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value4", val)
+}
+
+func TestMemcachedCacheCompareAndSwapChunked(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub,
+		"options.max_value", 1000,
+		"options.chunking", true,
+		"options.chunk_size", 300,
+	)
+	defer cache.Close(ctx, "")
+
+	large := strings.Repeat("0123456789", 100)
+	_, err = cache.Store(ctx, "", "large", large, 5000)
+	assert.Nil(t, err)
+
+	val, cas, err := cache.RetrieveWithCas(ctx, "", "large")
+	assert.Nil(t, err)
+	assert.Equal(t, large, val)
+
+	_, err = cache.Store(ctx, "", "large", "small", 5000)
+	assert.Nil(t, err)
+
+	// Chunks of the rejected value are removed
+	keys := len(stub.Keys())
+	ok, err := cache.CompareAndSwap(ctx, "", "large", large+large, cas, 5000)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Len(t, stub.Keys(), keys)
+
+	val, err = cache.Retrieve(ctx, "", "large")
+	assert.Nil(t, err)
+	assert.Equal(t, "small", val)
+}
+
+func TestMemcachedCacheUpdate(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[int](t, stub, "options.cas_retries", 1000)
+	defer cache.Close(ctx, "")
+
+	increment := func(old int, found bool) (int, error) {
+		return old + 1, nil
+	}
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 10; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := 0; index < 10; index++ {
+				_, err := cache.Update(ctx, "", "counter", increment, 5000)
+				assert.Nil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	val, err := cache.Retrieve(ctx, "", "counter")
+	assert.Nil(t, err)
+	assert.Equal(t, 100, val)
+
+	// Missing values are reported to the update function
+	val, err = cache.Update(ctx, "", "missing", func(old int, found bool) (int, error) {
+		assert.False(t, found)
+		return 10, nil
+	}, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, val)
+
+	// Errors of the update function are returned as is
+	_, err = cache.Update(ctx, "", "missing", func(old int, found bool) (int, error) {
+		return 0, cerr.NewBadRequestError("", "INVALID", "Invalid value")
+	}, 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID", err.(*cerr.ApplicationError).Code)
+
+	// Conflicts fail after retries are exhausted
+	limited := newStubCache[int](t, stub, "options.cas_retries", 2)
+	defer limited.Close(ctx, "")
+	attempts := 0
+	_, err = limited.Update(ctx, "", "counter", func(old int, found bool) (int, error) {
+		attempts++
+		cache.Store(ctx, "", "counter", old+100, 5000)
+		return old + 1, nil
+	}, 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Conflict, err.(*cerr.ApplicationError).Category)
+	assert.Equal(t, 3, attempts)
+}
@@ -0,0 +1,70 @@
+package test_cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheChunking(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	large := strings.Repeat("0123456789", 200)
+
+	// Large values are rejected without chunking
+	cache := newStubCache[string](t, stub, "options.max_value", 1000)
+	_, err = cache.Store(ctx, "", "large", large, 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.BadRequest, err.(*cerr.ApplicationError).Category)
+	cache.Close(ctx, "")
+
+	cache = newStubCache[string](t, stub,
+		"options.max_value", 1000,
+		"options.chunking", true,
+		"options.chunk_size", 300,
+	)
+	defer cache.Close(ctx, "")
+
+	_, err = cache.Store(ctx, "", "large", large, 5000)
+	assert.Nil(t, err)
+	assert.Len(t, stub.Keys(), 8)
+
+	val, err := cache.Retrieve(ctx, "", "large")
+	assert.Nil(t, err)
+	assert.Equal(t, large, val)
+
+	// Small values are stored as usual
+	_, err = cache.Store(ctx, "", "small", "value", 5000)
+	assert.Nil(t, err)
+	val, err = cache.Retrieve(ctx, "", "small")
+	assert.Nil(t, err)
+	assert.Equal(t, "value", val)
+
+	// Evicted chunk turns the value into a miss
+	for _, key := range stub.Keys() {
+		if strings.HasPrefix(key, "large#") {
+			stub.Delete(key)
+			break
+		}
+	}
+	val, err = cache.Retrieve(ctx, "", "large")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+
+	// Remove deletes the manifest with all chunks
+	_, err = cache.Store(ctx, "", "large", large, 5000)
+	assert.Nil(t, err)
+	err = cache.Remove(ctx, "", "large")
+	assert.Nil(t, err)
+	for _, key := range stub.Keys() {
+		assert.False(t, strings.HasPrefix(key, "large"))
+	}
+}
@@ -0,0 +1,113 @@
+package test_cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
+	memcache "github.com/pip-services3-gox/pip-services3-memcached-gox/cache"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+type point struct {
+	X, Y byte
+}
+
+type order struct {
+	Id    int
+	Items []int
+	Tags  map[string]string
+}
+
+func (p point) MarshalBinary() ([]byte, error) {
+	return []byte{p.X, p.Y}, nil
+}
+
+func (p *point) UnmarshalBinary(data []byte) error {
+	p.X, p.Y = data[0], data[1]
+	return nil
+}
+
+func newStubCacheUnopened[T any](stub *memfixture.MemcachedStub, tuples ...any) *memcache.MemcachedCache[T] {
+	cache := memcache.NewMemcachedCache[T]()
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+	)
+	config = config.Override(cconf.NewConfigParamsFromTuples(tuples...))
+	cache.Configure(context.Background(), config)
+	return cache
+}
+
+func newStubCache[T any](t *testing.T, stub *memfixture.MemcachedStub, tuples ...any) *memcache.MemcachedCache[T] {
+	cache := newStubCacheUnopened[T](stub, tuples...)
+	err := cache.Open(context.Background(), "")
+	assert.Nil(t, err)
+	return cache
+}
+
+func TestMemcachedCacheCodecs(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	jsonCache := newStubCache[any](t, stub)
+	defer jsonCache.Close(ctx, "")
+	fixture := memfixture.NewCacheFixture(jsonCache)
+	t.Run("Json:Store and Retrieve", fixture.TestStoreAndRetrieve)
+
+	gobCache := newStubCache[any](t, stub, "options.codec", "gob")
+	defer gobCache.Close(ctx, "")
+	fixture = memfixture.NewCacheFixture(gobCache)
+	t.Run("Gob:Store and Retrieve", fixture.TestStoreAndRetrieveTyped)
+
+	// Values are decoded by the codec they were stored with
+	_, err = jsonCache.Store(ctx, "", "mixed", "json value", 5000)
+	assert.Nil(t, err)
+	val, err := gobCache.Retrieve(ctx, "", "mixed")
+	assert.Nil(t, err)
+	assert.Equal(t, "json value", val)
+
+	now := time.Now()
+	timeCache := newStubCache[time.Time](t, stub, "options.codec", "msgpack")
+	defer timeCache.Close(ctx, "")
+	_, err = timeCache.Store(ctx, "", "time", now, 5000)
+	assert.Nil(t, err)
+	timeVal, err := timeCache.Retrieve(ctx, "", "time")
+	assert.Nil(t, err)
+	assert.True(t, now.Equal(timeVal))
+
+	// Msgpack keeps types of concrete values
+	orderCache := newStubCache[order](t, stub, "options.codec", "msgpack")
+	defer orderCache.Close(ctx, "")
+	orderValue := order{Id: 1000, Items: []int{1, 2, 3}, Tags: map[string]string{"a": "b"}}
+	_, err = orderCache.Store(ctx, "", "order", orderValue, 5000)
+	assert.Nil(t, err)
+	orderVal, err := orderCache.Retrieve(ctx, "", "order")
+	assert.Nil(t, err)
+	assert.Equal(t, orderValue, orderVal)
+
+	bytesCache := newStubCache[[]byte](t, stub, "options.codec", "bytes")
+	defer bytesCache.Close(ctx, "")
+	_, err = bytesCache.Store(ctx, "", "bytes", []byte("ABC"), 5000)
+	assert.Nil(t, err)
+	bytesVal, err := bytesCache.Retrieve(ctx, "", "bytes")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("ABC"), bytesVal)
+
+	pointCache := newStubCache[point](t, stub, "options.codec", "binary")
+	defer pointCache.Close(ctx, "")
+	_, err = pointCache.Store(ctx, "", "point", point{X: 1, Y: 2}, 5000)
+	assert.Nil(t, err)
+	pointVal, err := pointCache.Retrieve(ctx, "", "point")
+	assert.Nil(t, err)
+	assert.Equal(t, point{X: 1, Y: 2}, pointVal)
+
+	cache := newStubCacheUnopened[any](stub, "options.codec", "xml")
+	err = cache.Open(ctx, "")
+	assert.NotNil(t, err)
+}
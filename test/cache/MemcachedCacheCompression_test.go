@@ -0,0 +1,62 @@
+package test_cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheCompression(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	large := strings.Repeat("compressible value ", 1000)
+
+	plainCache := newStubCache[string](t, stub)
+	defer plainCache.Close(ctx, "")
+
+	for _, compression := range []string{"gzip", "snappy", "zstd"} {
+		cache := newStubCache[string](t, stub,
+			"options.compression", compression,
+			"options.compression_threshold", 100,
+		)
+
+		_, err = cache.Store(ctx, "", "large", large, 5000)
+		assert.Nil(t, err)
+		raw, _, ok := stub.Item("large")
+		assert.True(t, ok)
+		assert.Less(t, len(raw), len(large)/5)
+
+		_, err = cache.Store(ctx, "", "small", "small value", 5000)
+		assert.Nil(t, err)
+		raw, _, _ = stub.Item("small")
+		assert.Equal(t, "\"small value\"", string(raw))
+
+		// Compressed and uncompressed values are readable by both caches
+		val, err := plainCache.Retrieve(ctx, "", "large")
+		assert.Nil(t, err)
+		assert.Equal(t, large, val)
+
+		_, err = plainCache.Store(ctx, "", "plain", large, 5000)
+		assert.Nil(t, err)
+		val, err = cache.Retrieve(ctx, "", "plain")
+		assert.Nil(t, err)
+		assert.Equal(t, large, val)
+
+		cache.Close(ctx, "")
+	}
+
+	cache := newStubCacheUnopened[string](stub, "options.compression", "lz4")
+	err = cache.Open(ctx, "")
+	assert.NotNil(t, err)
+
+	cache = newStubCacheUnopened[string](stub, "options.compression_threshold", -1)
+	err = cache.Open(ctx, "")
+	assert.NotNil(t, err)
+}
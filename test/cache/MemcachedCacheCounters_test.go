@@ -0,0 +1,72 @@
+package test_cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memcache "github.com/pip-services3-gox/pip-services3-memcached-gox/cache"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheCounters(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[int](t, stub)
+	defer cache.Close(ctx, "")
+
+	// Missing counters are not created without initial value
+	_, err = cache.Increment(ctx, "", "counter", 1, memcache.NoInitialValue, 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.NotFound, err.(*cerr.ApplicationError).Category)
+
+	value, err := cache.Increment(ctx, "", "counter", 1, 10, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(10), value)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 10; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := 0; index < 10; index++ {
+				_, err := cache.Increment(ctx, "", "counter", 1, memcache.NoInitialValue, 5000)
+				assert.Nil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, err = cache.Decrement(ctx, "", "counter", 5, memcache.NoInitialValue, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(105), value)
+
+	// Counters are readable as numbers
+	val, err := cache.Retrieve(ctx, "", "counter")
+	assert.Nil(t, err)
+	assert.Equal(t, 105, val)
+
+	// Counters do not go below zero
+	value, err = cache.Decrement(ctx, "", "counter", 1000, memcache.NoInitialValue, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), value)
+
+	// Timeout is not checked when counters are not initialized
+	value, err = cache.Increment(ctx, "", "counter", 1, memcache.NoInitialValue, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1), value)
+
+	strings := newStubCache[string](t, stub)
+	defer strings.Close(ctx, "")
+	_, err = strings.Store(ctx, "", "text", "abc", 5000)
+	assert.Nil(t, err)
+	_, err = strings.Increment(ctx, "", "text", 1, 0, 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.BadRequest, err.(*cerr.ApplicationError).Category)
+}
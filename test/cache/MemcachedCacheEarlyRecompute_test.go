@@ -0,0 +1,86 @@
+package test_cache
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheEarlyRecompute(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	var calls int32
+	loader := func(ctx context.Context) (string, error) {
+		call := atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "value" + strconv.Itoa(int(call)), nil
+	}
+
+	// Fast values far from expiration are not recomputed
+	cache := newStubCache[string](t, stub, "options.early_recompute", true)
+	defer cache.Close(ctx, "")
+
+	val, err := cache.GetOrLoad(ctx, "", "key1", loader, 600000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	for index := 0; index < 10; index++ {
+		val, err = cache.GetOrLoad(ctx, "", "key1", loader, 600000)
+		assert.Nil(t, err)
+		assert.Equal(t, "value1", val)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Values with metadata are readable by Retrieve
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	// High beta makes recomputation certain
+	eager := newStubCache[string](t, stub,
+		"options.early_recompute", true,
+		"options.early_recompute_beta", 1000000,
+	)
+	defer eager.Close(ctx, "")
+
+	// The current value is returned while it is refreshed in background
+	val, err = eager.GetOrLoad(ctx, "", "key1", loader, 600000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", val)
+
+	// Values without expiration are never recomputed
+	forever := newStubCache[string](t, stub,
+		"options.early_recompute", true,
+		"options.early_recompute_beta", 1000000,
+		"options.allow_no_expiration", true,
+	)
+	defer forever.Close(ctx, "")
+
+	var loads int32
+	foreverLoader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&loads, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "forever", nil
+	}
+	for index := 0; index < 10; index++ {
+		val, err = forever.GetOrLoad(ctx, "", "key2", foreverLoader, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, "forever", val)
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
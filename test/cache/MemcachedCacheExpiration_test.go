@@ -0,0 +1,106 @@
+package test_cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func assertExpiresIn(t *testing.T, stub *memfixture.MemcachedStub, key string, timeout time.Duration) {
+	expiration, ok := stub.Expiration(key)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(timeout), expiration, 2*time.Second)
+}
+
+func TestMemcachedCacheTouch(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub)
+	defer cache.Close(ctx, "")
+
+	ok, err := cache.Touch(ctx, "", "key1", 60000)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	_, err = cache.Store(ctx, "", "key1", "value1", 5000)
+	assert.Nil(t, err)
+	assertExpiresIn(t, stub, "key1", 5*time.Second)
+
+	ok, err = cache.Touch(ctx, "", "key1", 60000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assertExpiresIn(t, stub, "key1", time.Minute)
+
+	val, err := cache.RetrieveAndTouch(ctx, "", "key1", 120000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	assertExpiresIn(t, stub, "key1", 2*time.Minute)
+
+	val, err = cache.RetrieveAndTouch(ctx, "", "missing", 120000)
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+}
+
+func TestMemcachedCacheTouchChunked(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub,
+		"options.max_value", 1000,
+		"options.chunking", true,
+		"options.chunk_size", 300,
+	)
+	defer cache.Close(ctx, "")
+
+	large := strings.Repeat("0123456789", 100)
+	_, err = cache.Store(ctx, "", "large", large, 5000)
+	assert.Nil(t, err)
+
+	ok, err := cache.Touch(ctx, "", "large", 60000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	for _, key := range stub.Keys() {
+		assertExpiresIn(t, stub, key, time.Minute)
+	}
+
+	val, err := cache.RetrieveAndTouch(ctx, "", "large", 120000)
+	assert.Nil(t, err)
+	assert.Equal(t, large, val)
+	for _, key := range stub.Keys() {
+		assertExpiresIn(t, stub, key, 2*time.Minute)
+	}
+}
+
+func TestMemcachedCacheSlidingExpiration(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub,
+		"options.sliding_expiration", true,
+		"options.sliding_timeout", 600000,
+	)
+	defer cache.Close(ctx, "")
+
+	_, err = cache.Store(ctx, "", "session", "value1", 5000)
+	assert.Nil(t, err)
+	assertExpiresIn(t, stub, "session", 5*time.Second)
+
+	val, err := cache.Retrieve(ctx, "", "session")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	assertExpiresIn(t, stub, "session", 10*time.Minute)
+}
@@ -0,0 +1,129 @@
+package test_cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheKeyNormalization(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub)
+	defer cache.Close(ctx, "")
+
+	// Keys with illegal characters are escaped
+	_, err = cache.Store(ctx, "", "user name\n1", "value1", 5000)
+	assert.Nil(t, err)
+	assert.Contains(t, stub.Keys(), "user%20name%0A1")
+
+	val, err := cache.Retrieve(ctx, "", "user name\n1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	// Escaped keys do not collide with literal ones
+	val, err = cache.Retrieve(ctx, "", "user%20name%0A1")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+
+	// Long keys are hashed
+	long1 := strings.Repeat("a", 300) + "1"
+	long2 := strings.Repeat("a", 300) + "2"
+	_, err = cache.Store(ctx, "", long1, "long1", 5000)
+	assert.Nil(t, err)
+	_, err = cache.Store(ctx, "", long2, "long2", 5000)
+	assert.Nil(t, err)
+	for _, key := range stub.Keys() {
+		assert.LessOrEqual(t, len(key), 250)
+	}
+
+	val, err = cache.Retrieve(ctx, "", long1)
+	assert.Nil(t, err)
+	assert.Equal(t, "long1", val)
+	val, err = cache.Retrieve(ctx, "", long2)
+	assert.Nil(t, err)
+	assert.Equal(t, "long2", val)
+
+	err = cache.Remove(ctx, "", long1)
+	assert.Nil(t, err)
+	val, err = cache.Retrieve(ctx, "", long1)
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+}
+
+func TestMemcachedCacheWithoutKeyNormalization(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub, "options.key_normalizer", "none")
+	defer cache.Close(ctx, "")
+
+	_, err = cache.Store(ctx, "", "user name", "value1", 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.BadRequest, err.(*cerr.ApplicationError).Category)
+	assert.Equal(t, "INVALID_KEY", err.(*cerr.ApplicationError).Code)
+
+	_, err = cache.Store(ctx, "", strings.Repeat("a", 251), "value1", 5000)
+	assert.NotNil(t, err)
+	assert.Equal(t, "KEY_TOO_LONG", err.(*cerr.ApplicationError).Code)
+}
+
+func TestMemcachedCacheKeyPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache1 := newStubCache[string](t, stub, "options.key_prefix", "service1:")
+	defer cache1.Close(ctx, "")
+	cache2 := newStubCache[string](t, stub, "options.key_prefix", "service2:")
+	defer cache2.Close(ctx, "")
+
+	_, err = cache1.Store(ctx, "", "key1", "value1", 5000)
+	assert.Nil(t, err)
+	_, err = cache2.Store(ctx, "", "key1", "value2", 5000)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"service1:key1", "service2:key1"}, stub.Keys())
+
+	val, err := cache1.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	val, err = cache2.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", val)
+
+	err = cache1.Remove(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"service2:key1"}, stub.Keys())
+
+	// Chunks of large values share the prefix
+	chunked := newStubCache[string](t, stub,
+		"options.key_prefix", "service3:",
+		"options.max_value", 1000,
+		"options.chunking", true,
+		"options.chunk_size", 300,
+	)
+	defer chunked.Close(ctx, "")
+
+	large := strings.Repeat("0123456789", 100)
+	_, err = chunked.Store(ctx, "", "large", large, 5000)
+	assert.Nil(t, err)
+	val, err = chunked.Retrieve(ctx, "", "large")
+	assert.Nil(t, err)
+	assert.Equal(t, large, val)
+	for _, key := range stub.Keys() {
+		assert.True(t, strings.HasPrefix(key, "service2:") || strings.HasPrefix(key, "service3:large"))
+	}
+}
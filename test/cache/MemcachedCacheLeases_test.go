@@ -0,0 +1,87 @@
+package test_cache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheLeases(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	// Two caches stand for two processes
+	cache1 := newStubCache[string](t, stub, "options.leases", true, "options.lease_wait", 2000)
+	defer cache1.Close(ctx, "")
+	cache2 := newStubCache[string](t, stub, "options.leases", true, "options.lease_wait", 2000)
+	defer cache2.Close(ctx, "")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		cache1.GetOrLoad(ctx, "", "key1", func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			return "value1", nil
+		}, 5000)
+	}()
+	<-started
+	assert.Contains(t, stub.Keys(), "key1%lease")
+
+	// The second client waits for the lease holder instead of loading
+	var calls int32
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		close(release)
+	}()
+	val, err := cache2.GetOrLoad(ctx, "", "key1", func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "value2", nil
+	}, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NotContains(t, stub.Keys(), "key1%lease")
+}
+
+func TestMemcachedCacheLeaseWaitBudget(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub, "options.leases", true, "options.lease_wait", 200)
+	defer cache.Close(ctx, "")
+
+	// User keys do not collide with lease items
+	ok, err := cache.Add(ctx, "", "key1%lease", "1", 5000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Contains(t, stub.Keys(), "key1%25lease")
+	stub.Delete("key1%25lease")
+
+	// A lease of a crashed client does not block loading after the wait budget
+	other := newStubCache[string](t, stub, "options.key_normalizer", "none")
+	defer other.Close(ctx, "")
+	ok, err = other.Add(ctx, "", "key1%lease", "1", 5000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	start := time.Now()
+	val, err := cache.GetOrLoad(ctx, "", "key1", func(ctx context.Context) (string, error) {
+		return "value1", nil
+	}, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
@@ -0,0 +1,128 @@
+package test_cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub)
+	defer cache.Close(ctx, "")
+
+	var calls int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "loaded", nil
+	}
+
+	// Concurrent callers share a single loader call
+	var wg sync.WaitGroup
+	for worker := 0; worker < 10; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := cache.GetOrLoad(ctx, "", "key1", loader, 5000)
+			assert.Nil(t, err)
+			assert.Equal(t, "loaded", val)
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Loaded values are cached
+	val, err := cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "loaded", val)
+	val, err = cache.GetOrLoad(ctx, "", "key1", loader, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, "loaded", val)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Cached zero values are hits
+	_, err = cache.Store(ctx, "", "empty", "", 5000)
+	assert.Nil(t, err)
+	val, err = cache.GetOrLoad(ctx, "", "empty", loader, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Loader errors are returned and not cached
+	failure := errors.New("database is down")
+	_, err = cache.GetOrLoad(ctx, "", "key2", func(ctx context.Context) (string, error) {
+		return "", failure
+	}, 5000)
+	assert.Equal(t, failure, err)
+	assert.False(t, cache.Contains(ctx, "", "key2"))
+
+	val, err = cache.GetOrLoad(ctx, "", "key2", func(ctx context.Context) (string, error) {
+		return "recovered", nil
+	}, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, "recovered", val)
+}
+
+func TestMemcachedCacheGetOrLoadPanic(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub, "options.stale_timeout", 60000)
+	defer cache.Close(ctx, "")
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (string, error) {
+		<-release
+		panic("loader is broken")
+	}
+
+	// Panics are returned as errors to all concurrent callers
+	var wg sync.WaitGroup
+	for worker := 0; worker < 5; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+			assert.NotNil(t, err)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// The key is released after the panic
+	val, err := cache.GetOrLoad(ctx, "", "key1", func(ctx context.Context) (string, error) {
+		return "value1", nil
+	}, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	// Panics of background refreshes do not crash the process
+	time.Sleep(1100 * time.Millisecond)
+	val, err = cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	time.Sleep(50 * time.Millisecond)
+
+	val, err = cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+}
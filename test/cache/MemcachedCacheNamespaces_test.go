@@ -0,0 +1,96 @@
+package test_cache
+
+import (
+	"context"
+	"testing"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheNamespaces(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	anyCache := newStubCache[any](t, stub, "options.namespaces", true)
+	defer anyCache.Close(ctx, "")
+	fixture := memfixture.NewCacheFixture(anyCache)
+	t.Run("Store and Retrieve", fixture.TestStoreAndRetrieve)
+
+	cache := newStubCache[string](t, stub, "options.namespaces", true)
+	defer cache.Close(ctx, "")
+
+	_, err = cache.Store(ctx, "", "product:1", "product1", 5000)
+	assert.Nil(t, err)
+	_, err = cache.Store(ctx, "", "product:2", "product2", 5000)
+	assert.Nil(t, err)
+	_, err = cache.Store(ctx, "", "order:1", "order1", 5000)
+	assert.Nil(t, err)
+	_, err = cache.Store(ctx, "", "plain", "plain", 5000)
+	assert.Nil(t, err)
+
+	val, err := cache.Retrieve(ctx, "", "product:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "product1", val)
+
+	err = cache.ClearNamespace(ctx, "", "product")
+	assert.Nil(t, err)
+
+	val, err = cache.Retrieve(ctx, "", "product:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+	assert.False(t, cache.Contains(ctx, "", "product:2"))
+
+	// Other namespaces and keys without namespace are kept
+	val, err = cache.Retrieve(ctx, "", "order:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "order1", val)
+	val, err = cache.Retrieve(ctx, "", "plain")
+	assert.Nil(t, err)
+	assert.Equal(t, "plain", val)
+
+	// New values are stored in the new generation
+	_, err = cache.Store(ctx, "", "product:1", "product1.v2", 5000)
+	assert.Nil(t, err)
+	val, err = cache.Retrieve(ctx, "", "product:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "product1.v2", val)
+
+	// Evicted counter does not bring old values back
+	stub.Delete("product%gen")
+	val, err = cache.Retrieve(ctx, "", "product:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+
+	// Clearing a namespace that was never used creates its counter
+	err = cache.ClearNamespace(ctx, "", "unused")
+	assert.Nil(t, err)
+	assert.Contains(t, stub.Keys(), "unused%gen")
+
+	err = cache.ClearNamespace(ctx, "", "invalid:ns")
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.BadRequest, err.(*cerr.ApplicationError).Category)
+}
+
+func TestMemcachedCacheNamespacesDisabled(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub)
+	defer cache.Close(ctx, "")
+
+	_, err = cache.Store(ctx, "", "product:1", "product1", 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"product:1"}, stub.Keys())
+
+	err = cache.ClearNamespace(ctx, "", "product")
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Misconfiguration, err.(*cerr.ApplicationError).Category)
+}
@@ -0,0 +1,177 @@
+package test_cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+// countReplicas returns the number of stubs that keep a key.
+func countReplicas(stubs []*memfixture.MemcachedStub, key string) int {
+	count := 0
+	for _, stub := range stubs {
+		if _, ok := stub.Expiration(key); ok {
+			count++
+		}
+	}
+	return count
+}
+
+func TestMemcachedCacheReplicas(t *testing.T) {
+	ctx := context.Background()
+
+	stubs := []*memfixture.MemcachedStub{}
+	for index := 0; index < 3; index++ {
+		stub, err := memfixture.NewMemcachedStub()
+		assert.Nil(t, err)
+		defer stub.Close()
+		stubs = append(stubs, stub)
+	}
+
+	cache := newClusterCache[string](t, stubs,
+		"options.distribution", "ketama",
+		"options.replicas", 2,
+		"options.retries", 0,
+	)
+	defer cache.Close(ctx, "")
+
+	// Values are written to distinct servers
+	keys := []string{}
+	for index := 0; index < 10; index++ {
+		key := "key" + strconv.Itoa(index)
+		keys = append(keys, key)
+		_, err := cache.Store(ctx, "", key, "value"+strconv.Itoa(index), 5000)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, countReplicas(stubs, key))
+	}
+
+	stored, err := cache.Add(ctx, "", "key0", "value", 5000)
+	assert.Nil(t, err)
+	assert.False(t, stored)
+	stored, err = cache.Add(ctx, "", "added", "value", 5000)
+	assert.Nil(t, err)
+	assert.True(t, stored)
+	assert.Equal(t, 2, countReplicas(stubs, "added"))
+
+	value, err := cache.Update(ctx, "", "key1", func(old string, found bool) (string, error) {
+		return old + "!", nil
+	}, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1!", value)
+
+	err = cache.Remove(ctx, "", "added")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countReplicas(stubs, "added"))
+
+	// Values of a failed server are read from other replicas
+	stubs[0].Close()
+	for index, key := range keys {
+		value, err := cache.Retrieve(ctx, "", key)
+		assert.Nil(t, err)
+		if index == 1 {
+			assert.Equal(t, "value1!", value)
+		} else {
+			assert.Equal(t, "value"+strconv.Itoa(index), value)
+		}
+	}
+	values, missing, err := cache.RetrieveMany(ctx, "", append(keys, "missing"))
+	assert.Nil(t, err)
+	assert.Len(t, values, 10)
+	assert.Equal(t, []string{"missing"}, missing)
+}
+
+func TestMemcachedCacheReplicasQuorum(t *testing.T) {
+	ctx := context.Background()
+
+	stubs := []*memfixture.MemcachedStub{}
+	for index := 0; index < 3; index++ {
+		stub, err := memfixture.NewMemcachedStub()
+		assert.Nil(t, err)
+		defer stub.Close()
+		stubs = append(stubs, stub)
+	}
+	stubs[0].Close()
+
+	cache := newClusterCache[string](t, stubs,
+		"options.distribution", "rendezvous",
+		"options.replicas", 2,
+		"options.quorum", 2,
+		"options.retries", 0,
+	)
+	defer cache.Close(ctx, "")
+
+	// Writes fail when a replica is on the failed server
+	failed := 0
+	for index := 0; index < 20; index++ {
+		key := "key" + strconv.Itoa(index)
+		_, err := cache.Store(ctx, "", key, "value", 5000)
+		if err != nil {
+			failed++
+			assert.Equal(t, "QUORUM_NOT_REACHED", err.(*cerr.ApplicationError).Code)
+			assert.Equal(t, 1, countReplicas(stubs[1:], key))
+		} else {
+			assert.Equal(t, 2, countReplicas(stubs[1:], key))
+		}
+	}
+	assert.Greater(t, failed, 0)
+	assert.Less(t, failed, 20)
+
+	// Quorum can not exceed the number of replicas
+	cache = newClusterCacheUnopened[string](stubs, "options.replicas", 2, "options.quorum", 3)
+	err := cache.Open(ctx, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Misconfiguration, err.(*cerr.ApplicationError).Category)
+}
+
+func TestMemcachedCacheReplicasConcat(t *testing.T) {
+	ctx := context.Background()
+
+	stubs := []*memfixture.MemcachedStub{}
+	for index := 0; index < 3; index++ {
+		stub, err := memfixture.NewMemcachedStub()
+		assert.Nil(t, err)
+		defer stub.Close()
+		stubs = append(stubs, stub)
+	}
+
+	cache := newClusterCache[[]byte](t, stubs,
+		"options.codec", "bytes",
+		"options.replicas", 2,
+		"options.quorum", 2,
+		"options.retries", 0,
+	)
+	defer cache.Close(ctx, "")
+
+	_, err := cache.Store(ctx, "", "log", []byte("abc"), 5000)
+	assert.Nil(t, err)
+	holders := []*memfixture.MemcachedStub{}
+	for _, stub := range stubs {
+		if _, ok := stub.Expiration("log"); ok {
+			holders = append(holders, stub)
+		}
+	}
+	assert.Len(t, holders, 2)
+
+	// Replicas missing the key are set to the value of the primary replica
+	holders[0].Delete("log")
+	appended, err := cache.Append(ctx, "", "log", []byte("def"))
+	assert.Nil(t, err)
+	if !appended {
+		// The key was removed from the primary replica
+		_, err = cache.Store(ctx, "", "log", []byte("abc"), 5000)
+		assert.Nil(t, err)
+		holders[1].Delete("log")
+		appended, err = cache.Append(ctx, "", "log", []byte("def"))
+		assert.Nil(t, err)
+	}
+	assert.True(t, appended)
+	for _, stub := range holders {
+		value, _, ok := stub.Item("log")
+		assert.True(t, ok)
+		assert.Equal(t, "abcdef", string(value))
+	}
+}
@@ -0,0 +1,59 @@
+package test_cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	cref "github.com/pip-services3-gox/pip-services3-commons-gox/refer"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheServerSelector(t *testing.T) {
+	ctx := context.Background()
+
+	stub1, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub1.Close()
+	stub2, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub2.Close()
+	stubs := []*memfixture.MemcachedStub{stub1, stub2}
+
+	// Selectors are chosen by configuration
+	for _, distribution := range []string{"modulo", "ketama", "rendezvous"} {
+		cache := newClusterCache[string](t, stubs, "options.distribution", distribution)
+		fixture := memfixture.NewCacheFixture(newClusterCache[any](t, stubs, "options.distribution", distribution))
+		t.Run(distribution+":Store and Retrieve", fixture.TestStoreAndRetrieve)
+
+		for index := 0; index < 10; index++ {
+			_, err = cache.Store(ctx, "", "key"+strconv.Itoa(index), "value", 5000)
+			assert.Nil(t, err)
+		}
+		values, _, err := cache.RetrieveMany(ctx, "", []string{"key0", "key5", "key9"})
+		assert.Nil(t, err)
+		assert.Len(t, values, 3)
+		cache.Close(ctx, "")
+	}
+
+	// Custom selectors are found in references
+	cache := newClusterCacheUnopened[string](stubs)
+	cache.SetReferences(ctx, cref.NewReferencesFromTuples(ctx,
+		cref.NewDescriptor("myservice", "server-selector", "prefix", "default", "1.0"),
+		memfixture.NewPrefixServerSelector("hot:", stub2.Host()+":"+stub2.Port()),
+	))
+	err = cache.Open(ctx, "")
+	assert.Nil(t, err)
+	defer cache.Close(ctx, "")
+
+	for index := 0; index < 5; index++ {
+		key := "hot:" + strconv.Itoa(index)
+		_, err = cache.Store(ctx, "", key, "value", 5000)
+		assert.Nil(t, err)
+		_, ok := stub2.Expiration(key)
+		assert.True(t, ok)
+		_, ok = stub1.Expiration(key)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,145 @@
+package test_cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheStaleValues(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub, "options.stale_timeout", 60000)
+	defer cache.Close(ctx, "")
+
+	value := "value1"
+	var loadErr error
+	loader := func(ctx context.Context) (string, error) {
+		return value, loadErr
+	}
+
+	result, err := cache.GetOrLoadWithState(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", result.Value)
+	assert.False(t, result.Stale)
+
+	// Values are kept for the stale period
+	assertExpiresIn(t, stub, "key1", 61*time.Second)
+
+	// Stale value is returned while it is refreshed in background
+	time.Sleep(1100 * time.Millisecond)
+	value = "value2"
+	result, err = cache.GetOrLoadWithState(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", result.Value)
+	assert.True(t, result.Stale)
+
+	time.Sleep(100 * time.Millisecond)
+	result, err = cache.GetOrLoadWithState(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", result.Value)
+	assert.False(t, result.Stale)
+
+	// Stale value is served while refreshes fail
+	time.Sleep(1100 * time.Millisecond)
+	value = "value3"
+	loadErr = errors.New("database is down")
+	for index := 0; index < 3; index++ {
+		val, err := cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+		assert.Nil(t, err)
+		assert.Equal(t, "value2", val)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Errors are returned when there is no stale value
+	_, err = cache.GetOrLoad(ctx, "", "key2", loader, 1000)
+	assert.Equal(t, loadErr, err)
+}
+
+func TestMemcachedCacheStaleRefreshRetry(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub,
+		"options.stale_timeout", 60000,
+		"options.refresh_retry", 500,
+	)
+	defer cache.Close(ctx, "")
+
+	var calls int32
+	var loadErr error
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "value1", loadErr
+	}
+
+	_, err = cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+
+	// Failed refresh is not retried until the retry delay passes
+	time.Sleep(1100 * time.Millisecond)
+	loadErr = errors.New("database is down")
+	for index := 0; index < 5; index++ {
+		val, err := cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+		assert.Nil(t, err)
+		assert.Equal(t, "value1", val)
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+
+	time.Sleep(300 * time.Millisecond)
+	_, err = cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestMemcachedCacheStaleNoExpiration(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub,
+		"options.stale_timeout", 60000,
+		"options.allow_no_expiration", true,
+	)
+	defer cache.Close(ctx, "")
+
+	var calls int32
+	loader := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "value1", nil
+	}
+
+	_, err = cache.GetOrLoad(ctx, "", "key1", loader, 0)
+	assert.Nil(t, err)
+
+	// Values without expiration are stored forever
+	expiration, ok := stub.Expiration("key1")
+	assert.True(t, ok)
+	assert.True(t, expiration.IsZero())
+
+	// and are never stale or refreshed
+	for index := 0; index < 3; index++ {
+		result, err := cache.GetOrLoadWithState(ctx, "", "key1", loader, 0)
+		assert.Nil(t, err)
+		assert.Equal(t, "value1", result.Value)
+		assert.False(t, result.Stale)
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,82 @@
+package test_cache
+
+import (
+	"context"
+	"testing"
+
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedCacheAddAndReplace(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[string](t, stub)
+	defer cache.Close(ctx, "")
+
+	// Replace does not create missing values
+	ok, err := cache.Replace(ctx, "", "key1", "value1", 5000)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.False(t, cache.Contains(ctx, "", "key1"))
+
+	// The first writer wins
+	ok, err = cache.Add(ctx, "", "key1", "value1", 5000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = cache.Add(ctx, "", "key1", "value2", 5000)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	val, err := cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	ok, err = cache.Replace(ctx, "", "key1", "value3", 5000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value3", val)
+}
+
+func TestMemcachedCacheAppendAndPrepend(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubCache[[]byte](t, stub, "options.codec", "bytes")
+	defer cache.Close(ctx, "")
+
+	ok, err := cache.Append(ctx, "", "log", []byte("line2\n"))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	_, err = cache.Store(ctx, "", "log", []byte("line2\n"), 5000)
+	assert.Nil(t, err)
+	ok, err = cache.Append(ctx, "", "log", []byte("line3\n"))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, err = cache.Prepend(ctx, "", "log", []byte("line1\n"))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	val, err := cache.Retrieve(ctx, "", "log")
+	assert.Nil(t, err)
+	assert.Equal(t, "line1\nline2\nline3\n", string(val))
+
+	// Values of other codecs can not be concatenated
+	json := newStubCache[string](t, stub)
+	defer json.Close(ctx, "")
+	_, err = json.Append(ctx, "", "log", "line4")
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Unsupported, err.(*cerr.ApplicationError).Category)
+}
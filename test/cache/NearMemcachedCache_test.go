@@ -0,0 +1,344 @@
+package test_cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
+	memcache "github.com/pip-services3-gox/pip-services3-memcached-gox/cache"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStubNearCache[T any](t *testing.T, stub *memfixture.MemcachedStub, tuples ...any) *memcache.NearMemcachedCache[T] {
+	cache := memcache.NewNearMemcachedCache[T]()
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+	)
+	cache.Configure(context.Background(), config.Override(cconf.NewConfigParamsFromTuples(tuples...)))
+	err := cache.Open(context.Background(), "")
+	assert.Nil(t, err)
+	return cache
+}
+
+func TestNearMemcachedCache(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubNearCache[any](t, stub)
+	defer cache.Close(ctx, "")
+
+	fixture := memfixture.NewCacheFixture(cache)
+	t.Run("Store and Retrieve", fixture.TestStoreAndRetrieve)
+	t.Run("Retrieve Expired", fixture.TestRetrieveExpired)
+	t.Run("Remove", fixture.TestRemove)
+}
+
+func TestNearMemcachedCacheTiers(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubNearCache[string](t, stub,
+		"options.local_cache.ttl", 300,
+		"options.negative_ttl", 10000,
+	)
+	defer cache.Close(ctx, "")
+	other := newStubCache[string](t, stub, "options.negative_ttl", 10000)
+	defer other.Close(ctx, "")
+
+	_, err = cache.Store(ctx, "", "key1", "value1", 5000)
+	assert.Nil(t, err)
+
+	val, err := cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	assert.Equal(t, memcache.NearCacheStats{LocalHits: 1, LocalMisses: 1, RemoteHits: 1}, cache.Stats())
+
+	// Changes of other processes are seen after the local timeout
+	_, err = other.Store(ctx, "", "key1", "value2", 5000)
+	assert.Nil(t, err)
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	time.Sleep(400 * time.Millisecond)
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", val)
+
+	// Writes through the component invalidate the local tier
+	_, err = cache.Store(ctx, "", "key1", "value3", 5000)
+	assert.Nil(t, err)
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value3", val)
+
+	err = cache.Remove(ctx, "", "key1")
+	assert.Nil(t, err)
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+	assert.Equal(t, int64(1), cache.Stats().RemoteMisses)
+
+	// Absent values are kept locally
+	err = other.StoreAbsent(ctx, "", "key2")
+	assert.Nil(t, err)
+	result, err := cache.RetrieveWithState(ctx, "", "key2")
+	assert.Nil(t, err)
+	assert.Equal(t, memcache.CacheStateAbsent, result.State)
+	_, err = other.Store(ctx, "", "key2", "value", 5000)
+	assert.Nil(t, err)
+	result, err = cache.RetrieveWithState(ctx, "", "key2")
+	assert.Nil(t, err)
+	assert.Equal(t, memcache.CacheStateAbsent, result.State)
+}
+
+func TestNearMemcachedCacheEviction(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubNearCache[string](t, stub, "options.local_cache.max_size", 2)
+	defer cache.Close(ctx, "")
+
+	err = cache.StoreMany(ctx, "", []memcache.CacheItem[string]{
+		memcache.NewCacheItem("key1", "value1", 5000),
+		memcache.NewCacheItem("key2", "value2", 5000),
+		memcache.NewCacheItem("key3", "value3", 5000),
+	})
+	assert.Nil(t, err)
+
+	values, missing, err := cache.RetrieveMany(ctx, "", []string{"key1", "key2", "key4"})
+	assert.Nil(t, err)
+	assert.Len(t, values, 2)
+	assert.Equal(t, []string{"key4"}, missing)
+	assert.Equal(t, memcache.NearCacheStats{LocalMisses: 3, RemoteHits: 2, RemoteMisses: 1}, cache.Stats())
+
+	// The least recently used key1 is evicted by key3
+	_, err = cache.Retrieve(ctx, "", "key2")
+	assert.Nil(t, err)
+	_, err = cache.Retrieve(ctx, "", "key3")
+	assert.Nil(t, err)
+	val, err := cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.LocalHits)
+	assert.Equal(t, int64(4), stats.RemoteHits)
+}
+
+func TestNearMemcachedCacheGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	cache := newStubNearCache[string](t, stub)
+	defer cache.Close(ctx, "")
+
+	calls := 0
+	loader := func(ctx context.Context) (string, error) {
+		calls++
+		return "loaded", nil
+	}
+
+	for index := 0; index < 3; index++ {
+		val, err := cache.GetOrLoad(ctx, "", "key1", loader, 5000)
+		assert.Nil(t, err)
+		assert.Equal(t, "loaded", val)
+	}
+	assert.Equal(t, 1, calls)
+	// Loaded values are kept locally when they are read again
+	assert.Equal(t, memcache.NearCacheStats{LocalHits: 1, LocalMisses: 2, RemoteHits: 1, RemoteMisses: 1}, cache.Stats())
+
+	_, err = cache.Update(ctx, "", "key1", func(old string, found bool) (string, error) {
+		return old + " and updated", nil
+	}, 5000)
+	assert.Nil(t, err)
+	val, err := cache.GetOrLoad(ctx, "", "key1", loader, 5000)
+	assert.Nil(t, err)
+	assert.Equal(t, "loaded and updated", val)
+}
+
+func TestNearMemcachedCacheRefresh(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	tuples := []any{
+		"options.local_cache.ttl", 60000,
+		"options.local_cache.coherence", "key",
+		"options.local_cache.check_interval", 0,
+		"options.early_recompute", true,
+		"options.early_recompute_beta", 1000000,
+	}
+	cache := newStubNearCache[string](t, stub, tuples...)
+	defer cache.Close(ctx, "")
+	peer := newStubNearCache[string](t, stub, tuples...)
+	defer peer.Close(ctx, "")
+
+	value := "value1"
+	loader := func(ctx context.Context) (string, error) {
+		time.Sleep(10 * time.Millisecond)
+		return value, nil
+	}
+
+	val, err := cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+	val, err = peer.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	// Values refreshed in background are invalidated in the local tiers
+	value = "value2"
+	val, err = cache.GetOrLoad(ctx, "", "key1", loader, 1000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	time.Sleep(100 * time.Millisecond)
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", val)
+	val, err = peer.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", val)
+
+	// Stamps are not changed by writes without NearMemcachedCache
+	plain := newStubCache[string](t, stub)
+	defer plain.Close(ctx, "")
+	_, err = plain.Store(ctx, "", "key1", "value3", 600000)
+	assert.Nil(t, err)
+
+	// Touches drop only the local value of the touching instance
+	_, err = peer.Touch(ctx, "", "key1", 600000)
+	assert.Nil(t, err)
+	val, err = peer.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value3", val)
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", val)
+
+	_, err = plain.Store(ctx, "", "key1", "value4", 600000)
+	assert.Nil(t, err)
+	val, err = peer.RetrieveAndTouch(ctx, "", "key1", 600000)
+	assert.Nil(t, err)
+	assert.Equal(t, "value4", val)
+	val, err = peer.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value4", val)
+	val, err = cache.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", val)
+}
+
+func TestNearMemcachedCacheKeyCoherence(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	tuples := []any{
+		"options.local_cache.ttl", 60000,
+		"options.local_cache.coherence", "key",
+		"options.local_cache.check_interval", 200,
+	}
+	cache1 := newStubNearCache[string](t, stub, tuples...)
+	defer cache1.Close(ctx, "")
+	cache2 := newStubNearCache[string](t, stub, tuples...)
+	defer cache2.Close(ctx, "")
+
+	_, err = cache1.Store(ctx, "", "key1", "value1", 5000)
+	assert.Nil(t, err)
+	_, err = cache1.Store(ctx, "", "key2", "value2", 5000)
+	assert.Nil(t, err)
+	values, _, err := cache1.RetrieveMany(ctx, "", []string{"key1", "key2"})
+	assert.Nil(t, err)
+	assert.Len(t, values, 2)
+
+	// Local values are served within the check interval
+	_, err = cache2.Store(ctx, "", "key1", "value3", 5000)
+	assert.Nil(t, err)
+	val, err := cache1.Retrieve(ctx, "", "key1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value1", val)
+
+	// Changed values are evicted after the check interval
+	time.Sleep(250 * time.Millisecond)
+	values, _, err = cache1.RetrieveMany(ctx, "", []string{"key1", "key2"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"key1": "value3", "key2": "value2"}, values)
+	assert.Equal(t, int64(2), cache1.Stats().LocalHits)
+
+	err = cache2.Remove(ctx, "", "key2")
+	assert.Nil(t, err)
+	time.Sleep(250 * time.Millisecond)
+	val, err = cache1.Retrieve(ctx, "", "key2")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+}
+
+func TestNearMemcachedCacheNamespaceCoherence(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	tuples := []any{
+		"options.namespaces", true,
+		"options.local_cache.ttl", 60000,
+		"options.local_cache.coherence", "namespace",
+		"options.local_cache.check_interval", 0,
+	}
+	cache1 := newStubNearCache[string](t, stub, tuples...)
+	defer cache1.Close(ctx, "")
+	cache2 := newStubNearCache[string](t, stub, tuples...)
+	defer cache2.Close(ctx, "")
+
+	_, err = cache1.Store(ctx, "", "product:1", "value1", 5000)
+	assert.Nil(t, err)
+	_, err = cache1.Store(ctx, "", "order:1", "value2", 5000)
+	assert.Nil(t, err)
+	_, err = cache1.Retrieve(ctx, "", "product:1")
+	assert.Nil(t, err)
+	_, err = cache1.Retrieve(ctx, "", "order:1")
+	assert.Nil(t, err)
+
+	// Clearing a namespace evicts local values of the namespace only
+	err = cache2.ClearNamespace(ctx, "", "product")
+	assert.Nil(t, err)
+	val, err := cache1.Retrieve(ctx, "", "product:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "", val)
+	val, err = cache1.Retrieve(ctx, "", "order:1")
+	assert.Nil(t, err)
+	assert.Equal(t, "value2", val)
+	assert.Equal(t, int64(1), cache1.Stats().LocalHits)
+
+	// A write of any key evicts other values of its namespace
+	_, err = cache2.Store(ctx, "", "order:2", "value3", 5000)
+	assert.Nil(t, err)
+	_, err = cache1.Retrieve(ctx, "", "order:1")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), cache1.Stats().LocalHits)
+}
@@ -0,0 +1,155 @@
+package test_connect
+
+import (
+	"crypto/md5"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"testing"
+
+	memcon "github.com/pip-services3-gox/pip-services3-memcached-gox/connect"
+	"github.com/stretchr/testify/assert"
+)
+
+type libmemcachedServer struct {
+	host   string
+	port   int
+	weight int
+}
+
+type libmemcachedPoint struct {
+	value uint32
+	index int
+}
+
+// libmemcachedHash is hashkit_md5 of libmemcached: the first 4 bytes of MD5 in little-endian order.
+func libmemcachedHash(digest [16]byte, alignment int) uint32 {
+	return uint32(digest[3+alignment*4])<<24 | uint32(digest[2+alignment*4])<<16 |
+		uint32(digest[1+alignment*4])<<8 | uint32(digest[alignment*4])
+}
+
+// libmemcachedContinuum is a line by line port of update_continuum of libmemcached (hosts.cc)
+// with MEMCACHED_BEHAVIOR_KETAMA_WEIGHTED, kept apart from KetamaServerSelector to check it.
+func libmemcachedContinuum(servers []libmemcachedServer) []libmemcachedPoint {
+	totalWeight := 0
+	for _, server := range servers {
+		totalWeight += server.weight
+	}
+
+	points := []libmemcachedPoint{}
+	for index, server := range servers {
+		pct := float32(server.weight) / float32(totalWeight)
+		pointerPerServer := uint32(math.Floor(float64(float32(float64(pct*160/4*float32(len(servers)))+0.0000000001)))) * 4
+		pointerPerHash := uint32(4)
+
+		for pointerIndex := uint32(1); pointerIndex <= pointerPerServer/pointerPerHash; pointerIndex++ {
+			var sortHost string
+			if server.port == 11211 {
+				sortHost = fmt.Sprintf("%s-%d", server.host, pointerIndex-1)
+			} else {
+				sortHost = fmt.Sprintf("%s:%d-%d", server.host, server.port, pointerIndex-1)
+			}
+			digest := md5.Sum([]byte(sortHost))
+			for x := 0; x < int(pointerPerHash); x++ {
+				points = append(points, libmemcachedPoint{value: libmemcachedHash(digest, x), index: index})
+			}
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].value < points[j].value
+	})
+	return points
+}
+
+// libmemcachedDispatch is dispatch_host of libmemcached for consistent distribution.
+func libmemcachedDispatch(points []libmemcachedPoint, key string) int {
+	hash := libmemcachedHash(md5.Sum([]byte(key)), 0)
+	left, right := 0, len(points)
+	for left < right {
+		middle := left + (right-left)/2
+		if points[middle].value < hash {
+			left = middle + 1
+		} else {
+			right = middle
+		}
+	}
+	if right == len(points) {
+		right = 0
+	}
+	return points[right].index
+}
+
+var ketamaClusters = map[string][]libmemcachedServer{
+	"default ports": {
+		{"10.0.1.1", 11211, 1},
+		{"10.0.1.2", 11211, 2},
+		{"10.0.1.3", 11211, 3},
+	},
+	"custom ports": {
+		{"10.0.1.1", 11211, 1},
+		{"10.0.1.1", 11212, 1},
+		{"10.0.1.2", 22122, 5},
+		{"10.0.1.3", 11311, 2},
+	},
+}
+
+func newKetamaSelector(t *testing.T, cluster []libmemcachedServer) *memcon.KetamaServerSelector {
+	servers := make([]*memcon.MemcachedServer, len(cluster))
+	for index, server := range cluster {
+		var err error
+		servers[index], err = memcon.NewMemcachedServer(server.host, server.port, server.weight)
+		assert.Nil(t, err)
+	}
+	selector := memcon.NewKetamaServerSelector()
+	err := selector.SetServers(servers)
+	assert.Nil(t, err)
+	return selector
+}
+
+func TestKetamaServerSelectorLibmemcached(t *testing.T) {
+	for name, cluster := range ketamaClusters {
+		selector := newKetamaSelector(t, cluster)
+		points := libmemcachedContinuum(cluster)
+		for index := 0; index < distributionKeys; index++ {
+			key := "key" + strconv.Itoa(index)
+			server, err := selector.PickServer(key)
+			assert.Nil(t, err)
+			expected := cluster[libmemcachedDispatch(points, key)]
+			assert.Equal(t, expected.host, server.Host, "%s: %s", name, key)
+			assert.Equal(t, expected.port, server.Port, "%s: %s", name, key)
+		}
+	}
+}
+
+func TestKetamaServerSelectorPlacement(t *testing.T) {
+	// Placement computed by the libmemcached port above, it pins the continuum against regressions
+	placement := map[string]map[string]string{
+		"default ports": {
+			"foo":          "10.0.1.3:11211",
+			"bar":          "10.0.1.3:11211",
+			"user:1001":    "10.0.1.2:11211",
+			"session:abc":  "10.0.1.2:11211",
+			"AXRxyUsFCcNv": "10.0.1.1:11211",
+			"memcached":    "10.0.1.2:11211",
+			"a":            "10.0.1.3:11211",
+		},
+		"custom ports": {
+			"foo":          "10.0.1.2:22122",
+			"bar":          "10.0.1.2:22122",
+			"user:1001":    "10.0.1.3:11311",
+			"session:abc":  "10.0.1.2:22122",
+			"AXRxyUsFCcNv": "10.0.1.1:11211",
+			"memcached":    "10.0.1.3:11311",
+			"a":            "10.0.1.3:11311",
+		},
+	}
+	for name, cluster := range ketamaClusters {
+		selector := newKetamaSelector(t, cluster)
+		for key, expected := range placement[name] {
+			server, err := selector.PickServer(key)
+			assert.Nil(t, err)
+			assert.Equal(t, expected, server.Host+":"+strconv.Itoa(server.Port), "%s: %s", name, key)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package test_connect
+
+import (
+	"context"
+	"hash/crc32"
+	"strconv"
+	"testing"
+
+	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	cref "github.com/pip-services3-gox/pip-services3-commons-gox/refer"
+	memcon "github.com/pip-services3-gox/pip-services3-memcached-gox/connect"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+const distributionKeys = 10000
+
+func newTestServers(t *testing.T, weights ...int) []*memcon.MemcachedServer {
+	servers := make([]*memcon.MemcachedServer, len(weights))
+	for index, weight := range weights {
+		server, err := memcon.NewMemcachedServer("127.0.0.1", 20001+index, weight)
+		assert.Nil(t, err)
+		servers[index] = server
+	}
+	return servers
+}
+
+// placeKeys maps test keys to names of their servers.
+func placeKeys(t *testing.T, selector memcon.IServerSelector, servers []*memcon.MemcachedServer) map[string]string {
+	err := selector.SetServers(servers)
+	assert.Nil(t, err)
+
+	placement := map[string]string{}
+	for index := 0; index < distributionKeys; index++ {
+		key := "key" + strconv.Itoa(index)
+		server, err := selector.PickServer(key)
+		assert.Nil(t, err)
+		placement[key] = server.Name()
+	}
+	return placement
+}
+
+// movedKeys counts keys placed on different servers and checks that
+// only keys of the changed server are moved when consistent is set.
+func movedKeys(t *testing.T, before map[string]string, after map[string]string, changed string, consistent bool) int {
+	moved := 0
+	for key, server := range before {
+		if after[key] == server {
+			continue
+		}
+		moved++
+		if consistent {
+			assert.True(t, server == changed || after[key] == changed, "key %s moved from %s to %s", key, server, after[key])
+		}
+	}
+	return moved
+}
+
+func TestServerSelectorsKeyMovement(t *testing.T) {
+	selectors := []struct {
+		name       string
+		create     func() memcon.IServerSelector
+		consistent bool
+	}{
+		{"modulo", func() memcon.IServerSelector { return memcon.NewModuloServerSelector() }, false},
+		{"ketama", func() memcon.IServerSelector { return memcon.NewKetamaServerSelector() }, true},
+		{"rendezvous", func() memcon.IServerSelector { return memcon.NewRendezvousServerSelector() }, true},
+	}
+
+	servers := newTestServers(t, 1, 1, 1, 1, 1)
+	for _, test := range selectors {
+		t.Run(test.name, func(t *testing.T) {
+			four := placeKeys(t, test.create(), servers[:4])
+			five := placeKeys(t, test.create(), servers)
+			added := movedKeys(t, four, five, servers[4].Name(), test.consistent)
+
+			withoutSecond := append([]*memcon.MemcachedServer{servers[0]}, servers[2:]...)
+			removed := movedKeys(t, five, placeKeys(t, test.create(), withoutSecond), servers[1].Name(), test.consistent)
+			t.Logf("%s moved %d keys of %d on added server and %d keys on removed server",
+				test.name, added, distributionKeys, removed)
+
+			if test.consistent {
+				// About 1/5 of keys are moved
+				assert.InDelta(t, distributionKeys/5, added, distributionKeys/10)
+				assert.InDelta(t, distributionKeys/5, removed, distributionKeys/10)
+			} else {
+				assert.Greater(t, added, distributionKeys*7/10)
+				assert.Greater(t, removed, distributionKeys*7/10)
+			}
+		})
+	}
+}
+
+func TestServerSelectorsWeights(t *testing.T) {
+	servers := newTestServers(t, 3, 1)
+	selectors := map[string]memcon.IServerSelector{
+		"ketama":     memcon.NewKetamaServerSelector(),
+		"rendezvous": memcon.NewRendezvousServerSelector(),
+	}
+	for name, selector := range selectors {
+		heavy := 0
+		for _, server := range placeKeys(t, selector, servers) {
+			if server == servers[0].Name() {
+				heavy++
+			}
+		}
+		assert.InDelta(t, distributionKeys*3/4, heavy, distributionKeys/20, name)
+	}
+}
+
+func TestServerSelectorsReplicas(t *testing.T) {
+	servers := newTestServers(t, 1, 1, 1, 1)
+	selectors := map[string]memcon.IServerSelector{
+		"modulo":     memcon.NewModuloServerSelector(),
+		"ketama":     memcon.NewKetamaServerSelector(),
+		"rendezvous": memcon.NewRendezvousServerSelector(),
+	}
+	for name, selector := range selectors {
+		err := selector.SetServers(servers)
+		assert.Nil(t, err)
+
+		for index := 0; index < 100; index++ {
+			key := "key" + strconv.Itoa(index)
+			primary, err := selector.PickServer(key)
+			assert.Nil(t, err)
+			replicas, err := selector.(memcon.IReplicaSelector).PickServers(key, 3)
+			assert.Nil(t, err)
+			assert.Len(t, replicas, 3, name)
+			assert.Equal(t, primary, replicas[0], name)
+
+			picked := map[string]bool{}
+			for _, replica := range replicas {
+				picked[replica.Name()] = true
+			}
+			assert.Len(t, picked, 3, name)
+		}
+
+		// Replicas are limited by the number of servers
+		replicas, err := selector.(memcon.IReplicaSelector).PickServers("key", 10)
+		assert.Nil(t, err)
+		assert.Len(t, replicas, 4, name)
+	}
+}
+
+func TestMemcachedConnectionServerSelector(t *testing.T) {
+	ctx := context.Background()
+
+	config := cconf.NewConfigParamsFromTuples(
+		"connections.0.host", "127.0.0.1",
+		"connections.0.port", 20001,
+		"connections.1.host", "127.0.0.1",
+		"connections.1.port", 20002,
+		"connections.2.host", "127.0.0.1",
+		"connections.2.port", 20003,
+	)
+	selector := memfixture.NewPrefixServerSelector("hot:", "127.0.0.1:20003")
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, config)
+	connection.SetReferences(ctx, cref.NewReferencesFromTuples(ctx,
+		cref.NewDescriptor("myservice", "server-selector", "prefix", "default", "1.0"), selector,
+	))
+	err := connection.Open(ctx, "")
+	assert.Nil(t, err)
+	defer connection.Close(ctx, "")
+
+	groups, errs := connection.GroupKeys("", []string{"hot:1", "hot:2", "key1", "key2", "key3"})
+	assert.Len(t, errs, 0)
+	assert.ElementsMatch(t, []string{"hot:1", "hot:2"}, groups["127.0.0.1:20003"])
+	assert.Len(t, append(groups["127.0.0.1:20001"], groups["127.0.0.1:20002"]...), 3)
+}
+
+func TestMemcachedConnectionDistributionOptions(t *testing.T) {
+	ctx := context.Background()
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", "localhost",
+		"options.distribution", "random",
+	))
+	err := connection.Open(ctx, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_OPTION", err.(*cerr.ApplicationError).Code)
+
+	connection = memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", "localhost",
+		"connection.weight", 0,
+	))
+	err = connection.Open(ctx, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_WEIGHT", err.(*cerr.ApplicationError).Code)
+
+	// Weights are read from connection parameters
+	connection = memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connections.0.host", "127.0.0.1",
+		"connections.0.port", 20001,
+		"connections.0.weight", 3,
+		"connections.1.host", "127.0.0.1",
+		"connections.1.port", 20002,
+		"options.distribution", "rendezvous",
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	defer connection.Close(ctx, "")
+
+	keys := make([]string, distributionKeys)
+	for index := range keys {
+		keys[index] = "key" + strconv.Itoa(index)
+	}
+	groups, errs := connection.GroupKeys("", keys)
+	assert.Len(t, errs, 0)
+	assert.InDelta(t, distributionKeys*3/4, len(groups["127.0.0.1:20001"]), distributionKeys/20)
+}
+
+func TestMemcachedConnectionModuloOrder(t *testing.T) {
+	ctx := context.Background()
+
+	// Servers are listed out of order
+	ports := []int{20003, 20001, 20002}
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connections.0.host", "127.0.0.1",
+		"connections.0.port", ports[0],
+		"connections.1.host", "127.0.0.1",
+		"connections.1.port", ports[1],
+		"connections.2.host", "127.0.0.1",
+		"connections.2.port", ports[2],
+	))
+	err := connection.Open(ctx, "")
+	assert.Nil(t, err)
+	defer connection.Close(ctx, "")
+
+	// Modulo distribution places keys by the configured order as gomemcache does
+	keys := make([]string, 100)
+	for index := range keys {
+		keys[index] = "key" + strconv.Itoa(index)
+	}
+	groups, errs := connection.GroupKeys("", keys)
+	assert.Len(t, errs, 0)
+	for _, key := range keys {
+		port := ports[crc32.ChecksumIEEE([]byte(key))%uint32(len(ports))]
+		assert.Contains(t, groups["127.0.0.1:"+strconv.Itoa(port)], key)
+	}
+}
@@ -0,0 +1,463 @@
+package test_connect
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	cref "github.com/pip-services3-gox/pip-services3-commons-gox/refer"
+	cauth "github.com/pip-services3-gox/pip-services3-components-gox/auth"
+	memcon "github.com/pip-services3-gox/pip-services3-memcached-gox/connect"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemcachedConnectionInvalidOptions(t *testing.T) {
+	ctx := context.Background()
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", "localhost",
+		"connection.port", 11211,
+		"options.pool_size", 0,
+	))
+
+	err := connection.Open(ctx, "123")
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Misconfiguration, err.(*cerr.ApplicationError).Category)
+	assert.False(t, connection.IsOpen())
+
+	connection = memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", "localhost",
+		"options.max_key_size", 1000,
+	))
+	err = connection.Open(ctx, "123")
+	assert.NotNil(t, err)
+	assert.Equal(t, "INVALID_OPTION", err.(*cerr.ApplicationError).Code)
+}
+
+func TestMemcachedConnectionExpiration(t *testing.T) {
+	ctx := context.Background()
+	day := int64(24 * 60 * 60 * 1000)
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", "localhost",
+	))
+
+	// Sub-second timeouts are rounded up
+	expiration, err := connection.Expiration("", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), expiration)
+	expiration, err = connection.Expiration("", 1500)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), expiration)
+
+	// Long timeouts are reduced to max_expiration
+	expiration, err = connection.Expiration("", 40*day)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(30*24*60*60), expiration)
+
+	// No expiration is rejected by default
+	_, err = connection.Expiration("", memcon.NoExpiration)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.BadRequest, err.(*cerr.ApplicationError).Category)
+	_, err = connection.Expiration("", -1000)
+	assert.NotNil(t, err)
+
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"options.max_expiration", 60*24*60*60,
+		"options.allow_no_expiration", true,
+	))
+
+	expiration, err = connection.Expiration("", memcon.NoExpiration)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(0), expiration)
+
+	// Timeouts longer than 30 days are sent as Unix time
+	expiration, err = connection.Expiration("", 40*day)
+	assert.Nil(t, err)
+	assert.InDelta(t, time.Now().Add(40*24*time.Hour).Unix(), int64(expiration), 2)
+	expiration, err = connection.Expiration("", 30*day)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(30*24*60*60), expiration)
+
+	// Absolute time does not overflow 32 bits
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"options.max_expiration", math.MaxInt32,
+	))
+	expiration, err = connection.Expiration("", math.MaxInt32*1000)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(math.MaxInt32), expiration)
+
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"options.max_expiration", int64(math.MaxInt32)+1,
+	))
+	err = connection.Open(ctx, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Misconfiguration, err.(*cerr.ApplicationError).Category)
+}
+
+func TestMemcachedConnectionRetriesAndIdle(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"options.retries", 1,
+		"options.idle", 100,
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	defer connection.Close(ctx, "")
+
+	set := func(client *memcache.Client) error {
+		return client.Set(&memcache.Item{Key: "key1", Value: []byte("ABC")})
+	}
+
+	err = connection.Execute("", set)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stub.Connections())
+
+	// Broken pooled connection is retried
+	stub.DropConnections()
+	<-time.After(50 * time.Millisecond)
+	err = connection.Execute("", set)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, stub.Connections())
+
+	// Idle connection is reestablished
+	<-time.After(200 * time.Millisecond)
+	err = connection.Execute("", set)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, stub.Connections())
+}
+
+func TestMemcachedConnectionRetriesNotIdempotent(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"options.retries", 3,
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	defer connection.Close(ctx, "")
+
+	// Idempotent commands are retried when the response is lost
+	stub.DropAfter("set")
+	err = connection.Execute("", func(client *memcache.Client) error {
+		return client.Set(&memcache.Item{Key: "counter", Value: []byte("10")})
+	})
+	assert.Nil(t, err)
+
+	// Applied commands are not sent again
+	stub.DropAfter("add")
+	err = connection.ExecuteOnce("", func(client *memcache.Client) error {
+		return client.Add(&memcache.Item{Key: "lock", Value: []byte("lock")})
+	})
+	assert.NotNil(t, err)
+	assert.NotEqual(t, memcache.ErrNotStored, err)
+	_, _, ok := stub.Item("lock")
+	assert.True(t, ok)
+
+	stub.DropAfter("incr")
+	err = connection.ExecuteOnce("", func(client *memcache.Client) error {
+		_, err := client.Increment("counter", 1)
+		return err
+	})
+	assert.NotNil(t, err)
+	value, _, _ := stub.Item("counter")
+	assert.Equal(t, "11", string(value))
+}
+
+func TestMemcachedConnectionDeadServer(t *testing.T) {
+	ctx := context.Background()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", "127.0.0.1",
+		"connection.port", port,
+		"options.retries", 0,
+		"options.failures", 1,
+		"options.retry", 60000,
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	defer connection.Close(ctx, "")
+
+	get := func(client *memcache.Client) error {
+		_, err := client.Get("key1")
+		return err
+	}
+
+	err = connection.Execute("", get)
+	assert.NotNil(t, err)
+
+	err = connection.Execute("", get)
+	assert.NotNil(t, err)
+	assert.Equal(t, "NO_SERVERS", err.(*cerr.ApplicationError).Code)
+}
+
+func TestMemcachedConnectionSlowCommands(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"options.timeout", 100,
+		"options.retries", 0,
+		"options.failures", 1,
+		"options.retry", 60000,
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	defer connection.Close(ctx, "")
+
+	get := func(client *memcache.Client) error {
+		_, err := client.Get("key1")
+		if err == memcache.ErrCacheMiss {
+			return nil
+		}
+		return err
+	}
+
+	err = connection.Execute("", get)
+	assert.Nil(t, err)
+
+	// Timeouts of slow commands do not mark the server as dead
+	stub.DelayNext("gets", 300*time.Millisecond)
+	err = connection.Execute("", get)
+	assert.NotNil(t, err)
+
+	err = connection.Execute("", get)
+	assert.Nil(t, err)
+}
+
+func TestMemcachedConnectionAuthentication(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+	stub.RequireAuth("user1", "pass123")
+
+	set := func(client *memcache.Client) error {
+		return client.Set(&memcache.Item{Key: "key1", Value: []byte("ABC")})
+	}
+
+	// Text protocol authentication by default with configured credentials
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"credential.username", "user1",
+		"credential.password", "pass123",
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	err = connection.Execute("", set)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stub.Authentications())
+	connection.Close(ctx, "")
+
+	// Text protocol authentication with credentials from credential store
+	store := cauth.NewEmptyMemoryCredentialStore()
+	store.ReadCredentials(cconf.NewConfigParamsFromTuples(
+		"memcached.username", "user1",
+		"memcached.password", "pass123",
+	))
+	references := cref.NewReferencesFromTuples(ctx,
+		cref.NewDescriptor("pip-services", "credential_store", "memory", "default", "1.0"), store,
+	)
+
+	connection = memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"credential.store_key", "memcached",
+	))
+	connection.SetReferences(ctx, references)
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	err = connection.Execute("", set)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, stub.Authentications())
+	connection.Close(ctx, "")
+
+	// User names are separated from passwords by a space
+	connection = memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"credential.username", "user 1",
+		"credential.password", "pass123",
+	))
+	err = connection.Open(ctx, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Misconfiguration, err.(*cerr.ApplicationError).Category)
+
+	// Wrong password
+	connection = memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"credential.username", "user1",
+		"credential.password", "wrong",
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	err = connection.Execute("", set)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Unauthorized, err.(*cerr.ApplicationError).Category)
+	connection.Close(ctx, "")
+}
+
+func TestMemcachedConnectionTls(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	caCert, caKey := newCertificate(t, dir, "ca", nil, nil)
+	newCertificate(t, dir, "server", caCert, caKey)
+	newCertificate(t, dir, "client", caCert, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(caCert)
+	keyPair, err := tls.LoadX509KeyPair(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"))
+	assert.Nil(t, err)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{keyPair},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	assert.Nil(t, err)
+	stub := memfixture.NewMemcachedStubWithListener(listener)
+	defer stub.Close()
+	stub.RequireAuth("user1", "pass123")
+
+	set := func(client *memcache.Client) error {
+		return client.Set(&memcache.Item{Key: "key1", Value: []byte("ABC")})
+	}
+
+	connection := memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.protocol", "tls",
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"credential.username", "user1",
+		"credential.password", "pass123",
+		"options.ssl_ca_file", filepath.Join(dir, "ca.crt"),
+		"options.ssl_cert_file", filepath.Join(dir, "client.crt"),
+		"options.ssl_key_file", filepath.Join(dir, "client.key"),
+		"options.ssl_server_name", "localhost",
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	err = connection.Execute("", set)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stub.Authentications())
+	connection.Close(ctx, "")
+
+	// Server certificate is not trusted without CA bundle
+	connection = memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.protocol", "tls",
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"options.ssl_cert_file", filepath.Join(dir, "client.crt"),
+		"options.ssl_key_file", filepath.Join(dir, "client.key"),
+	))
+	err = connection.Open(ctx, "")
+	assert.Nil(t, err)
+	err = connection.Execute("", set)
+	assert.NotNil(t, err)
+	connection.Close(ctx, "")
+
+	// Missing CA file
+	connection = memcon.NewMemcachedConnection()
+	connection.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connection.protocol", "tls",
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+		"options.ssl_ca_file", filepath.Join(dir, "missing.crt"),
+	))
+	err = connection.Open(ctx, "")
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.Misconfiguration, err.(*cerr.ApplicationError).Category)
+}
+
+func newCertificate(t *testing.T, dir string, name string,
+	parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent = template
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	err = os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	assert.Nil(t, err)
+	err = os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	assert.Nil(t, err)
+
+	return cert, key
+}
@@ -104,6 +104,29 @@ func (c *CacheFixture) TestStoreAndRetrieve(t *testing.T) {
 	assert.Nil(t, val)
 }
 
+func (c *CacheFixture) TestStoreAndRetrieveTyped(t *testing.T) {
+	ctx := context.Background()
+
+	values := map[string]any{
+		KEY1: VALUE1, KEY2: VALUE2, KEY3: VALUE3, KEY4: VALUE4, KEY5: VALUE5, KEY6: VALUE6,
+	}
+	for key, value := range values {
+		_, err := c.cache.Store(ctx, "", key, value, 5000)
+		assert.Nil(t, err)
+	}
+
+	for key, value := range values {
+		val, err := c.cache.Retrieve(ctx, "", key)
+		assert.Nil(t, err)
+
+		if expected, ok := value.(time.Time); ok {
+			assert.True(t, expected.Equal(val.(time.Time)))
+		} else {
+			assert.Equal(t, value, val)
+		}
+	}
+}
+
 func (c *CacheFixture) TestRetrieveExpired(t *testing.T) {
 	ctx := context.Background()
 
@@ -0,0 +1,492 @@
+package test_fixture
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type stubItem struct {
+	value      []byte
+	flags      uint32
+	expiration time.Time
+	cas        uint64
+}
+
+// MemcachedStub is an in-process stand-in for Memcached server
+// that implements the text protocol commands used by the components.
+type MemcachedStub struct {
+	listener    net.Listener
+	mtx         sync.Mutex
+	items       map[string]*stubItem
+	cas         uint64
+	connections int
+	conns       map[net.Conn]bool
+	username    string
+	password    string
+	authCount   int
+	dropAfter   map[string]int
+	delays      map[string][]time.Duration
+}
+
+// NewMemcachedStub starts a stub server on a random local port.
+func NewMemcachedStub() (*MemcachedStub, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	return NewMemcachedStubWithListener(listener), nil
+}
+
+// NewMemcachedStubWithListener starts a stub server on a given listener.
+func NewMemcachedStubWithListener(listener net.Listener) *MemcachedStub {
+	c := &MemcachedStub{
+		listener:  listener,
+		items:     map[string]*stubItem{},
+		conns:     map[net.Conn]bool{},
+		dropAfter: map[string]int{},
+		delays:    map[string][]time.Duration{},
+	}
+	go c.serve()
+	return c
+}
+
+// Host returns the host the stub listens on.
+func (c *MemcachedStub) Host() string {
+	host, _, _ := net.SplitHostPort(c.listener.Addr().String())
+	return host
+}
+
+// Port returns the port the stub listens on.
+func (c *MemcachedStub) Port() string {
+	_, port, _ := net.SplitHostPort(c.listener.Addr().String())
+	return port
+}
+
+// RequireAuth makes the stub to require text protocol authentication
+// with given credentials on each connection, as memcached started with -Y option.
+func (c *MemcachedStub) RequireAuth(username string, password string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.username = username
+	c.password = password
+}
+
+// Authentications returns the number of successful authentications.
+func (c *MemcachedStub) Authentications() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.authCount
+}
+
+// Item returns raw value and flags of a stored item.
+func (c *MemcachedStub) Item(key string) ([]byte, uint32, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	item := c.lookup(key)
+	if item == nil {
+		return nil, 0, false
+	}
+	return item.value, item.flags, true
+}
+
+// Expiration returns expiration time of a stored item, zero time for items that never expire.
+func (c *MemcachedStub) Expiration(key string) (time.Time, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	item := c.lookup(key)
+	if item == nil {
+		return time.Time{}, false
+	}
+	return item.expiration, true
+}
+
+// Keys returns keys of all stored items.
+func (c *MemcachedStub) Keys() []string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	keys := make([]string, 0, len(c.items))
+	for key := range c.items {
+		if c.lookup(key) != nil {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Delete removes a stored item to simulate eviction.
+func (c *MemcachedStub) Delete(key string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.items, key)
+}
+
+// DropAfter makes the stub to close the connection without a response after it applies
+// the next command with a given name, as a server that fails after a write.
+func (c *MemcachedStub) DropAfter(command string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.dropAfter[command]++
+}
+
+func (c *MemcachedStub) shouldDrop(command string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.dropAfter[command] == 0 {
+		return false
+	}
+	c.dropAfter[command]--
+	return true
+}
+
+// DelayNext makes the stub to wait before it applies the next command with a given name,
+// as a server that processes a slow command.
+func (c *MemcachedStub) DelayNext(command string, delay time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.delays[command] = append(c.delays[command], delay)
+}
+
+func (c *MemcachedStub) delay(command string) {
+	c.mtx.Lock()
+	delays := c.delays[command]
+	if len(delays) == 0 {
+		c.mtx.Unlock()
+		return
+	}
+	c.delays[command] = delays[1:]
+	c.mtx.Unlock()
+	time.Sleep(delays[0])
+}
+
+// Connections returns the number of accepted connections.
+func (c *MemcachedStub) Connections() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.connections
+}
+
+// DropConnections closes all open client connections.
+func (c *MemcachedStub) DropConnections() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for conn := range c.conns {
+		conn.Close()
+	}
+}
+
+// Close stops the stub server.
+func (c *MemcachedStub) Close() error {
+	err := c.listener.Close()
+	c.DropConnections()
+	return err
+}
+
+func (c *MemcachedStub) serve() {
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		c.mtx.Lock()
+		c.connections++
+		c.conns[conn] = true
+		c.mtx.Unlock()
+
+		go c.handle(conn)
+	}
+}
+
+func (c *MemcachedStub) handle(conn net.Conn) {
+	defer func() {
+		c.mtx.Lock()
+		delete(c.conns, conn)
+		c.mtx.Unlock()
+		conn.Close()
+	}()
+
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	c.mtx.Lock()
+	authenticated := c.username == ""
+	c.mtx.Unlock()
+
+	for {
+		if !authenticated {
+			if authenticated = c.authenticate(rw); !authenticated {
+				rw.Flush()
+				return
+			}
+			if rw.Flush() != nil {
+				return
+			}
+			continue
+		}
+
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		c.delay(args[0])
+		if !c.execute(rw, args) {
+			return
+		}
+		if c.shouldDrop(args[0]) {
+			return
+		}
+		if rw.Flush() != nil {
+			return
+		}
+	}
+}
+
+// authenticate performs text protocol authentication.
+// Returns true if the client is authenticated.
+func (c *MemcachedStub) authenticate(rw *bufio.ReadWriter) bool {
+	line, err := rw.ReadString('\n')
+	if err != nil {
+		return false
+	}
+	args := strings.Fields(line)
+	if len(args) < 5 || args[0] != "set" {
+		rw.WriteString("CLIENT_ERROR unauthenticated\r\n")
+		return false
+	}
+	size, _ := strconv.Atoi(args[4])
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(rw, data); err != nil {
+		return false
+	}
+	var username, password string
+	if parts := strings.SplitN(string(data[:size]), " ", 2); len(parts) == 2 {
+		username, password = parts[0], parts[1]
+	}
+	if !c.checkCredentials(username, password) {
+		rw.WriteString("CLIENT_ERROR authentication failure\r\n")
+		return false
+	}
+	rw.WriteString("STORED\r\n")
+	return true
+}
+
+func (c *MemcachedStub) checkCredentials(username string, password string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if username != c.username || password != c.password {
+		return false
+	}
+	c.authCount++
+	return true
+}
+
+func (c *MemcachedStub) execute(rw *bufio.ReadWriter, args []string) bool {
+	switch args[0] {
+	case "get", "gets":
+		c.get(rw, args[1:], args[0] == "gets", nil)
+	case "gat", "gats":
+		if len(args) < 3 {
+			rw.WriteString("ERROR\r\n")
+			return true
+		}
+		exptime, _ := strconv.ParseInt(args[1], 10, 64)
+		c.get(rw, args[2:], args[0] == "gats", &exptime)
+	case "set", "add", "replace", "append", "prepend", "cas":
+		return c.store(rw, args)
+	case "delete":
+		c.mtx.Lock()
+		if c.lookup(args[1]) != nil {
+			delete(c.items, args[1])
+			rw.WriteString("DELETED\r\n")
+		} else {
+			rw.WriteString("NOT_FOUND\r\n")
+		}
+		c.mtx.Unlock()
+	case "incr", "decr":
+		c.incrDecr(rw, args)
+	case "touch":
+		exptime, _ := strconv.ParseInt(args[2], 10, 64)
+		c.mtx.Lock()
+		if item := c.lookup(args[1]); item != nil {
+			item.expiration = stubExpiration(exptime)
+			rw.WriteString("TOUCHED\r\n")
+		} else {
+			rw.WriteString("NOT_FOUND\r\n")
+		}
+		c.mtx.Unlock()
+	case "version":
+		rw.WriteString("VERSION 1.6.0-stub\r\n")
+	case "flush_all":
+		c.mtx.Lock()
+		c.items = map[string]*stubItem{}
+		c.mtx.Unlock()
+		rw.WriteString("OK\r\n")
+	case "quit":
+		return false
+	default:
+		rw.WriteString("ERROR\r\n")
+	}
+	return true
+}
+
+func (c *MemcachedStub) lookup(key string) *stubItem {
+	item, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	if !item.expiration.IsZero() && !time.Now().Before(item.expiration) {
+		delete(c.items, key)
+		return nil
+	}
+	return item
+}
+
+func (c *MemcachedStub) get(rw *bufio.ReadWriter, keys []string, withCas bool, exptime *int64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, key := range keys {
+		item := c.lookup(key)
+		if item == nil {
+			continue
+		}
+		if exptime != nil {
+			item.expiration = stubExpiration(*exptime)
+		}
+		rw.WriteString("VALUE " + key + " " + strconv.FormatUint(uint64(item.flags), 10) + " " + strconv.Itoa(len(item.value)))
+		if withCas {
+			rw.WriteString(" " + strconv.FormatUint(item.cas, 10))
+		}
+		rw.WriteString("\r\n")
+		rw.Write(item.value)
+		rw.WriteString("\r\n")
+	}
+	rw.WriteString("END\r\n")
+}
+
+func (c *MemcachedStub) store(rw *bufio.ReadWriter, args []string) bool {
+	if len(args) < 5 {
+		rw.WriteString("ERROR\r\n")
+		return true
+	}
+	key := args[1]
+	flags, _ := strconv.ParseUint(args[2], 10, 32)
+	exptime, _ := strconv.ParseInt(args[3], 10, 64)
+	size, err := strconv.Atoi(args[4])
+	if err != nil || size < 0 {
+		rw.WriteString("CLIENT_ERROR bad data chunk\r\n")
+		return false
+	}
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(rw, data); err != nil {
+		return false
+	}
+	data = data[:size]
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	existing := c.lookup(key)
+	switch args[0] {
+	case "add":
+		if existing != nil {
+			rw.WriteString("NOT_STORED\r\n")
+			return true
+		}
+	case "replace":
+		if existing == nil {
+			rw.WriteString("NOT_STORED\r\n")
+			return true
+		}
+	case "append", "prepend":
+		if existing == nil {
+			rw.WriteString("NOT_STORED\r\n")
+			return true
+		}
+		if args[0] == "append" {
+			existing.value = append(existing.value, data...)
+		} else {
+			existing.value = append(data, existing.value...)
+		}
+		c.cas++
+		existing.cas = c.cas
+		rw.WriteString("STORED\r\n")
+		return true
+	case "cas":
+		if len(args) < 6 {
+			rw.WriteString("ERROR\r\n")
+			return true
+		}
+		if existing == nil {
+			rw.WriteString("NOT_FOUND\r\n")
+			return true
+		}
+		if strconv.FormatUint(existing.cas, 10) != args[5] {
+			rw.WriteString("EXISTS\r\n")
+			return true
+		}
+	}
+
+	c.cas++
+	c.items[key] = &stubItem{
+		value:      data,
+		flags:      uint32(flags),
+		expiration: stubExpiration(exptime),
+		cas:        c.cas,
+	}
+	rw.WriteString("STORED\r\n")
+	return true
+}
+
+func (c *MemcachedStub) incrDecr(rw *bufio.ReadWriter, args []string) {
+	delta, err := strconv.ParseUint(args[2], 10, 64)
+	if err != nil {
+		rw.WriteString("CLIENT_ERROR invalid numeric delta argument\r\n")
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	item := c.lookup(args[1])
+	if item == nil {
+		rw.WriteString("NOT_FOUND\r\n")
+		return
+	}
+	value, err := strconv.ParseUint(string(item.value), 10, 64)
+	if err != nil {
+		rw.WriteString("CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+		return
+	}
+	if args[0] == "incr" {
+		value += delta
+	} else if delta > value {
+		value = 0
+	} else {
+		value -= delta
+	}
+	item.value = []byte(strconv.FormatUint(value, 10))
+	c.cas++
+	item.cas = c.cas
+	rw.WriteString(string(item.value) + "\r\n")
+}
+
+func stubExpiration(exptime int64) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		return time.Now()
+	case exptime > 60*60*24*30:
+		return time.Unix(exptime, 0)
+	default:
+		return time.Now().Add(time.Duration(exptime) * time.Second)
+	}
+}
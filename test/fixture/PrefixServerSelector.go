@@ -0,0 +1,44 @@
+package test_fixture
+
+import (
+	"strings"
+
+	memcon "github.com/pip-services3-gox/pip-services3-memcached-gox/connect"
+)
+
+// PrefixServerSelector pins keys with a prefix to the server with an address
+// and distributes other keys between the rest of servers with rendezvous hashing.
+type PrefixServerSelector struct {
+	prefix  string
+	address string
+	pinned  *memcon.MemcachedServer
+	others  *memcon.RendezvousServerSelector
+}
+
+// NewPrefixServerSelector creates a new instance of the selector.
+func NewPrefixServerSelector(prefix string, address string) *PrefixServerSelector {
+	return &PrefixServerSelector{
+		prefix:  prefix,
+		address: address,
+		others:  memcon.NewRendezvousServerSelector(),
+	}
+}
+
+func (c *PrefixServerSelector) SetServers(servers []*memcon.MemcachedServer) error {
+	others := []*memcon.MemcachedServer{}
+	for _, server := range servers {
+		if server.Addr.String() == c.address {
+			c.pinned = server
+		} else {
+			others = append(others, server)
+		}
+	}
+	return c.others.SetServers(others)
+}
+
+func (c *PrefixServerSelector) PickServer(key string) (*memcon.MemcachedServer, error) {
+	if c.pinned != nil && strings.HasPrefix(key, c.prefix) {
+		return c.pinned, nil
+	}
+	return c.others.PickServer(key)
+}
@@ -0,0 +1,134 @@
+package test_lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cconf "github.com/pip-services3-gox/pip-services3-commons-gox/config"
+	cerr "github.com/pip-services3-gox/pip-services3-commons-gox/errors"
+	cref "github.com/pip-services3-gox/pip-services3-commons-gox/refer"
+	memlock "github.com/pip-services3-gox/pip-services3-memcached-gox/lock"
+	memfixture "github.com/pip-services3-gox/pip-services3-memcached-gox/test/fixture"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStubLock(t *testing.T, stub *memfixture.MemcachedStub, tuples ...any) *memlock.MemcachedLock {
+	lock := memlock.NewMemcachedLock()
+	config := cconf.NewConfigParamsFromTuples(
+		"connection.host", stub.Host(),
+		"connection.port", stub.Port(),
+	)
+	config = config.Override(cconf.NewConfigParamsFromTuples(tuples...))
+	lock.Configure(context.Background(), config)
+	err := lock.Open(context.Background(), "")
+	assert.Nil(t, err)
+	return lock
+}
+
+func TestMemcachedLockKeyPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	lock1 := newStubLock(t, stub, "options.key_prefix", "service1:")
+	defer lock1.Close(ctx, "")
+	lock2 := newStubLock(t, stub, "options.key_prefix", "service2:")
+	defer lock2.Close(ctx, "")
+
+	fixture := memfixture.NewLockFixture(lock1)
+	t.Run("Try Acquire Lock", fixture.TestTryAcquireLock)
+
+	ok, err := lock1.TryAcquireLock(ctx, "", "lock1", 5000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Contains(t, stub.Keys(), "service1:lock1")
+
+	// The same lock name in another namespace is independent
+	ok, err = lock2.TryAcquireLock(ctx, "", "lock1", 5000)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	err = lock1.ReleaseLock(ctx, "", "lock1")
+	assert.Nil(t, err)
+	assert.NotContains(t, stub.Keys(), "service1:lock1")
+	assert.Contains(t, stub.Keys(), "service2:lock1")
+}
+
+func TestMemcachedLockExpiration(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	lock := newStubLock(t, stub)
+	defer lock.Close(ctx, "")
+
+	// Short locks expire instead of being held forever
+	ok, err := lock.TryAcquireLock(ctx, "", "lock1", 500)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	expiration, found := stub.Expiration("lock1")
+	assert.True(t, found)
+	assert.False(t, expiration.IsZero())
+	assert.WithinDuration(t, time.Now().Add(time.Second), expiration, time.Second)
+
+	_, err = lock.TryAcquireLock(ctx, "", "lock2", 0)
+	assert.NotNil(t, err)
+	assert.Equal(t, cerr.BadRequest, err.(*cerr.ApplicationError).Category)
+}
+
+func TestMemcachedLockLostResponse(t *testing.T) {
+	ctx := context.Background()
+
+	stub, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub.Close()
+
+	lock := newStubLock(t, stub, "options.retries", 3)
+	defer lock.Close(ctx, "")
+
+	// A retry would find the lock added by the failed attempt and report it as taken
+	stub.DropAfter("add")
+	ok, err := lock.TryAcquireLock(ctx, "", "lock1", 5000)
+	assert.NotNil(t, err)
+	assert.False(t, ok)
+	assert.Contains(t, stub.Keys(), "lock1")
+}
+
+func TestMemcachedLockServerSelector(t *testing.T) {
+	ctx := context.Background()
+
+	stub1, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub1.Close()
+	stub2, err := memfixture.NewMemcachedStub()
+	assert.Nil(t, err)
+	defer stub2.Close()
+
+	lock := memlock.NewMemcachedLock()
+	lock.Configure(ctx, cconf.NewConfigParamsFromTuples(
+		"connections.0.host", stub1.Host(),
+		"connections.0.port", stub1.Port(),
+		"connections.1.host", stub2.Host(),
+		"connections.1.port", stub2.Port(),
+	))
+	lock.SetReferences(ctx, cref.NewReferencesFromTuples(ctx,
+		cref.NewDescriptor("myservice", "server-selector", "prefix", "default", "1.0"),
+		memfixture.NewPrefixServerSelector("jobs:", stub2.Host()+":"+stub2.Port()),
+	))
+	err = lock.Open(ctx, "")
+	assert.Nil(t, err)
+	defer lock.Close(ctx, "")
+
+	result, err := lock.TryAcquireLock(ctx, "", "jobs:1", 5000)
+	assert.Nil(t, err)
+	assert.True(t, result)
+	_, ok := stub2.Expiration("jobs:1")
+	assert.True(t, ok)
+	_, ok = stub1.Expiration("jobs:1")
+	assert.False(t, ok)
+}